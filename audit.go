@@ -0,0 +1,151 @@
+package ttail
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// auditSampleLines is a number of lines taken at every sample offset
+	auditSampleLines = 10
+	// auditExamples is a number of kept lines without time
+	auditExamples = 5
+)
+
+// AuditReport of time extraction from lines sampled over file
+type AuditReport struct {
+	// Lines is a number of sampled lines, Matched of them have time
+	Lines   int
+	Matched int
+	// Clusters are ranges of file with lines without time
+	Clusters []AuditCluster
+	// Examples of distinct lines without time
+	Examples []string
+	// Types of config parsing time better than options of file
+	Types []TypeMatch
+}
+
+// AuditCluster is a range of file from the first to the end of
+// the last sampled line without time
+type AuditCluster struct {
+	Start int64
+	End   int64
+	Lines int
+}
+
+// Rate return share of sampled lines with time
+func (r *AuditReport) Rate() float64 {
+	if r.Lines == 0 {
+		return 0
+	}
+	return float64(r.Matched) / float64(r.Lines)
+}
+
+// Audit read lines at samples offsets evenly spread over file and check
+// that time is extracted from them, types of conf with better rate
+// are suggested if conf is not nil
+func (t *TFile) Audit(samples int, conf Config) (*AuditReport, error) {
+	if s, ok := t.file.(io.Seeker); ok {
+		size, err := s.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		t.size = size
+	}
+	if samples < 1 {
+		samples = 1
+	}
+	report := &AuditReport{}
+	var lines [][]byte
+	lastFailed := -2
+	// next is an offset after lines of previous sample, samples of small file overlap
+	var next int64
+	buf := make([]byte, t.opts.bufSize)
+	for i := 0; i < samples; i++ {
+		start := t.size / int64(samples) * int64(i)
+		atLine := start == 0
+		if start < next {
+			start, atLine = next, true
+		}
+		if start >= t.size {
+			break
+		}
+		offset := start
+		n, err := t.file.ReadAt(buf, offset)
+		t.stats.BytesRead += int64(n)
+		if err != nil && err != io.EOF {
+			return nil, errors.Wrap(err, "Audit")
+		}
+		chunk := buf[:n]
+		if !atLine {
+			// skip line started before offset
+			idx := bytes.IndexByte(chunk, '\n')
+			if idx < 0 {
+				continue
+			}
+			offset += int64(idx + 1)
+			chunk = chunk[idx+1:]
+		}
+		// range of lines without time in sample
+		var failed int
+		var failStart, failEnd int64
+		for taken := 0; taken < auditSampleLines; taken++ {
+			idx := bytes.IndexByte(chunk, '\n')
+			if idx < 0 {
+				break
+			}
+			line := chunk[:idx]
+			chunk = chunk[idx+1:]
+			lineStart := next
+			if taken == 0 {
+				lineStart = offset
+			}
+			next = start + int64(n-len(chunk))
+			report.Lines++
+			lines = append(lines, append([]byte(nil), line...))
+			if _, err := t.ParseTime(line); err == nil {
+				report.Matched++
+				continue
+			}
+			if failed == 0 {
+				failStart = lineStart
+			}
+			failed, failEnd = failed+1, next
+			report.addExample(line)
+		}
+		if failed == 0 {
+			continue
+		}
+		// failures of adjacent or overlapping samples are one cluster
+		if c := len(report.Clusters); c > 0 && (lastFailed == i-1 || failStart <= report.Clusters[c-1].End) {
+			report.Clusters[c-1].End = failEnd
+			report.Clusters[c-1].Lines += failed
+		} else {
+			report.Clusters = append(report.Clusters, AuditCluster{Start: failStart, End: failEnd, Lines: failed})
+		}
+		lastFailed = i
+	}
+	if conf != nil {
+		for _, m := range RankLogTypes(lines, conf) {
+			if m.Rate > report.Rate() {
+				report.Types = append(report.Types, m)
+			}
+		}
+	}
+	return report, nil
+}
+
+// addExample keep line without time if it is not kept yet
+func (r *AuditReport) addExample(line []byte) {
+	if len(r.Examples) >= auditExamples {
+		return
+	}
+	for _, e := range r.Examples {
+		if e == string(line) {
+			return
+		}
+	}
+	r.Examples = append(r.Examples, string(line))
+}
@@ -0,0 +1,26 @@
+package ttail
+
+// BuiltinTypes are log types available without config, types of config
+// file with the same names override them
+var BuiltinTypes = Config{
+	// dotted is a german and russian style, e.g. 25.12.2023 10:30:45
+	"dotted": {
+		TimeReStr:  `(?:^|[^\d.])(\d{1,2}\.\d{1,2}\.\d{4} \d{1,2}:\d\d:\d\d)`,
+		TimeLayout: "2.1.2006 15:04:05",
+	},
+	// slashed is a british and french style, e.g. 25/12/2023 10:30:45
+	"slashed": {
+		TimeReStr:  `(?:^|[^\d/])(\d{1,2}/\d{1,2}/\d{4} \d{1,2}:\d\d:\d\d)`,
+		TimeLayout: "2/1/2006 15:04:05",
+	},
+	// cjk is a chinese and japanese style, e.g. 2023年12月25日 10:30:45
+	"cjk": {
+		TimeReStr:  `(\d{4}年\d{1,2}月\d{1,2}日 \d{1,2}:\d\d:\d\d)`,
+		TimeLayout: "2006年1月2日 15:04:05",
+	},
+	// korean style, e.g. 2023년 12월 25일 10:30:45
+	"korean": {
+		TimeReStr:  `(\d{4}년 \d{1,2}월 \d{1,2}일 \d{1,2}:\d\d:\d\d)`,
+		TimeLayout: "2006년 1월 2일 15:04:05",
+	},
+}
@@ -0,0 +1,155 @@
+package ttail
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// chunkMaxSize limit growth of chunks read by NewestFirst
+const chunkMaxSize = 4 << 20
+
+// Chunk is a part of time window of complete lines in time order
+type Chunk struct {
+	// Offset of chunk in file
+	Offset int64
+	Data   []byte
+}
+
+// Chunks iterate over time window by chunks from the newest to the oldest
+type Chunks struct {
+	t    *TFile
+	size int64
+	max  int64
+	// lo and hi are bounds of part of window not read yet
+	lo, hi int64
+	chunk  Chunk
+	err    error
+}
+
+// NewestFirst return iterator over time window found by FindPosition by chunks
+// from the newest lines to the oldest ones, so interactive consumer may show
+// the end of large window at once while older lines are read, the first chunk
+// has about size bytes and next ones grow twice up to 4MB
+//
+//	chunks := tfile.NewestFirst(64 << 10)
+//	for chunks.Next() {
+//		c := chunks.Chunk()
+//	}
+//	err := chunks.Err()
+func (t *TFile) NewestFirst(size int64) *Chunks {
+	if size <= 0 {
+		size = t.opts.bufSize
+	}
+	c := &Chunks{t: t, size: size, max: chunkMaxSize}
+	if size > c.max {
+		c.max = size
+	}
+	if t.opts.descending {
+		c.lo, c.hi = t.bound(), t.offset
+		return c
+	}
+	if s, ok := t.file.(io.Seeker); ok && t.size == 0 {
+		// FindPosition is not called, e.g. offset is set by SetOffset
+		if t.size, c.err = s.Seek(0, io.SeekEnd); c.err != nil {
+			return c
+		}
+	}
+	c.lo, c.hi = t.offset, t.bound()
+	return c
+}
+
+// Next read the next older chunk, false means start of window or error
+func (c *Chunks) Next() bool {
+	for c.err == nil && c.lo < c.hi {
+		var ok bool
+		if c.t.opts.descending {
+			ok = c.head()
+		} else {
+			ok = c.tail()
+		}
+		if c.err != nil {
+			return false
+		}
+		if !ok {
+			// line is longer than chunk
+			c.size *= 2
+			continue
+		}
+		if c.size *= 2; c.size > c.max {
+			c.size = c.max
+		}
+		if len(c.chunk.Data) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// read part of window
+func (c *Chunks) read(start, end int64) []byte {
+	data := make([]byte, end-start)
+	n, err := c.t.file.ReadAt(data, start)
+	if err != nil && !(err == io.EOF && n == len(data)) {
+		c.err = errors.Wrap(err, "Chunks")
+	}
+	return data
+}
+
+// tail take complete lines of the end of window of ascending log
+func (c *Chunks) tail() bool {
+	start := c.hi - c.size
+	if start < c.lo {
+		start = c.lo
+	}
+	data := c.read(start, c.hi)
+	if start > c.lo {
+		// the first line may begin before chunk
+		idx := bytes.IndexByte(data, '\n') + 1
+		if idx == 0 || idx == len(data) {
+			return false
+		}
+		data, start = data[idx:], start+int64(idx)
+	}
+	c.hi = start
+	c.chunk = Chunk{Offset: start}
+	c.chunk.Data, c.err = c.t.FilterLines(data)
+	return true
+}
+
+// head take complete lines of the start of window of descending log
+// and put them in time order
+func (c *Chunks) head() bool {
+	end := c.lo + c.size
+	if end > c.hi {
+		end = c.hi
+	}
+	data := c.read(c.lo, end)
+	if end < c.hi {
+		// the last line may end after chunk
+		idx := bytes.LastIndexByte(data, '\n') + 1
+		if idx == 0 {
+			return false
+		}
+		data = data[:idx]
+	}
+	c.chunk = Chunk{Offset: c.lo}
+	c.lo += int64(len(data))
+	rr := &reverseReader{r: bytes.NewReader(data), pos: int64(len(data)), chunk: int64(len(data))}
+	if data, c.err = ioutil.ReadAll(rr); c.err == nil {
+		c.chunk.Data, c.err = c.t.FilterLines(data)
+	}
+	return true
+}
+
+// Chunk return the current chunk
+func (c *Chunks) Chunk() Chunk {
+	return c.chunk
+}
+
+// Err return error of reading
+func (c *Chunks) Err() error {
+	return c.err
+}
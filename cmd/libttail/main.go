@@ -0,0 +1,182 @@
+// Package main is a C API of ttail time search, build shared library with
+//
+//	go build -buildmode=c-shared -o libttail.so ./cmd/libttail
+//
+// it writes libttail.h with declarations of exported functions
+package main
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/sakateka/ttail"
+)
+
+// handle of opened file, C code gets its number
+type handle struct {
+	file   *os.File
+	tfile  *ttail.TFile
+	reader io.Reader
+	err    *C.char
+}
+
+var handles = struct {
+	sync.Mutex
+	next int64
+	m    map[int64]*handle
+	// err of ttail_open without handle
+	err *C.char
+}{m: map[int64]*handle{}}
+
+func getHandle(h C.int64_t) *handle {
+	handles.Lock()
+	defer handles.Unlock()
+	return handles.m[int64(h)]
+}
+
+// setError keep message of err until the next call for handle
+func (hd *handle) setError(err error) {
+	if hd.err != nil {
+		C.free(unsafe.Pointer(hd.err))
+		hd.err = nil
+	}
+	if err != nil {
+		hd.err = C.CString(err.Error())
+	}
+}
+
+// ttail_open open file for time search of lines in last duration_ns nanoseconds,
+// from time of last line if from_last_line is not zero, log_type is a type
+// of config or empty for default, return handle or -1 on error
+//
+//export ttail_open
+func ttail_open(path, log_type *C.char, duration_ns C.int64_t, from_last_line C.int) C.int64_t {
+	opts := []ttail.TimeFileOptions{
+		ttail.WithDuration(time.Duration(duration_ns)),
+		ttail.WithTimeFromLastLine(from_last_line != 0),
+	}
+	logType := C.GoString(log_type)
+	var err error
+	if logType != "" {
+		var typeOpts []ttail.TimeFileOptions
+		typeOpts, err = ttail.OptionsFromConfig(logType)
+		opts = append(opts, typeOpts...)
+	}
+	var file *os.File
+	if err == nil {
+		file, err = os.Open(C.GoString(path))
+	}
+
+	handles.Lock()
+	defer handles.Unlock()
+	if handles.err != nil {
+		C.free(unsafe.Pointer(handles.err))
+		handles.err = nil
+	}
+	if err != nil {
+		handles.err = C.CString(err.Error())
+		return -1
+	}
+	handles.next++
+	handles.m[handles.next] = &handle{file: file, tfile: ttail.NewTimeFile(file, opts...)}
+	return C.int64_t(handles.next)
+}
+
+// ttail_find_position search start of time window, return its offset or -1 on error,
+// offset is the file size when there are no lines in window
+//
+//export ttail_find_position
+func ttail_find_position(h C.int64_t) C.int64_t {
+	hd := getHandle(h)
+	if hd == nil {
+		return -1
+	}
+	err := hd.tfile.FindPosition()
+	if err == io.EOF {
+		hd.tfile.SetOffset(hd.tfile.Size())
+		err = nil
+	}
+	hd.setError(err)
+	hd.reader = nil
+	if err != nil {
+		return -1
+	}
+	return C.int64_t(hd.tfile.Offset())
+}
+
+// ttail_read read up to size bytes of window into buf,
+// return number of bytes, 0 at the end of file or -1 on error
+//
+//export ttail_read
+func ttail_read(h C.int64_t, buf *C.char, size C.int64_t) C.int64_t {
+	hd := getHandle(h)
+	if hd == nil || size < 0 {
+		return -1
+	}
+	if hd.reader == nil {
+		r, err := hd.tfile.GetReader()
+		hd.setError(err)
+		if err != nil {
+			return -1
+		}
+		hd.reader = r
+	}
+	if size == 0 {
+		return 0
+	}
+	p := (*[1 << 30]byte)(unsafe.Pointer(buf))[:size:size]
+	n, err := io.ReadFull(hd.reader, p)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	hd.setError(err)
+	if err != nil {
+		return -1
+	}
+	return C.int64_t(n)
+}
+
+// ttail_error return message of the last error of handle or of ttail_open
+// for handle 0, it is valid until the next call with handle
+//
+//export ttail_error
+func ttail_error(h C.int64_t) *C.char {
+	if h == 0 {
+		handles.Lock()
+		defer handles.Unlock()
+		return handles.err
+	}
+	if hd := getHandle(h); hd != nil {
+		return hd.err
+	}
+	return nil
+}
+
+// ttail_close close file of handle
+//
+//export ttail_close
+func ttail_close(h C.int64_t) C.int {
+	handles.Lock()
+	hd := handles.m[int64(h)]
+	delete(handles.m, int64(h))
+	handles.Unlock()
+	if hd == nil {
+		return -1
+	}
+	hd.setError(nil)
+	if err := hd.file.Close(); err != nil {
+		return -1
+	}
+	return 0
+}
+
+func main() {}
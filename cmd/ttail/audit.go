@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sakateka/ttail"
+)
+
+var flagSamples int
+
+var auditCommand = &command{
+	name: "audit",
+	args: "file [file ...]",
+	help: "check share of lines with time of log type in files and suggest better types",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&flagLogType, "t", "", "use a type of log (default tskv)")
+		fs.IntVar(&flagSamples, "samples", 100, "number of offsets spread over file to sample lines at")
+	},
+	run: runAudit,
+}
+
+func runAudit(fs *flag.FlagSet) error {
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	var opts []ttail.TimeFileOptions
+	if flagLogType != "" {
+		logOpts, err := ttail.OptionsFromConfig(flagLogType)
+		if err != nil {
+			return err
+		}
+		opts = logOpts
+	}
+	// suggestions are optional, audit works without config
+	conf, _ := ttail.DefaultConfig()
+	for _, fname := range fs.Args() {
+		src, size, err := openSource(fname)
+		if err != nil {
+			fileError(openErrorKind(err), fname, err)
+			continue
+		}
+		report, err := ttail.NewTimeReader(src, size, opts...).Audit(flagSamples, conf)
+		src.Close()
+		if err != nil {
+			fileError("read", fname, err)
+			continue
+		}
+		printAudit(fname, report)
+	}
+	return nil
+}
+
+func printAudit(fname string, r *ttail.AuditReport) {
+	logType := flagLogType
+	if logType == "" {
+		logType = "default"
+	}
+	fmt.Printf("%s: %.1f%% of %d sampled lines have time of %s type\n", fname, r.Rate()*100, r.Lines, logType)
+	if len(r.Clusters) > 0 {
+		clusters := make([]string, 0, len(r.Clusters))
+		for _, c := range r.Clusters {
+			clusters = append(clusters, fmt.Sprintf("%s-%s (%d lines)", humanBytes(c.Start), humanBytes(c.End), c.Lines))
+		}
+		fmt.Printf("  no time at: %s\n", strings.Join(clusters, ", "))
+	}
+	for _, line := range r.Examples {
+		if len(line) > 120 {
+			line = line[:120] + "..."
+		}
+		fmt.Printf("  e.g. %q\n", line)
+	}
+	if len(r.Types) > 0 {
+		types := make([]string, 0, len(r.Types))
+		for i, m := range r.Types {
+			if i == 3 {
+				break
+			}
+			types = append(types, fmt.Sprintf("%s %.1f%%", m.Name, m.Rate*100))
+		}
+		fmt.Printf("  closer types: %s\n", strings.Join(types, ", "))
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io"
+	"sync"
+
+	"github.com/sakateka/ttail"
+	"go.uber.org/zap"
+)
+
+// autoLogType detects log type of every file by its first lines
+const autoLogType = "auto"
+
+var detected = struct {
+	sync.Mutex
+	types map[string]string
+}{types: map[string]string{}}
+
+// detectLogType return options of log type detected in src
+func detectLogType(fname string, src source, size int64) []ttail.TimeFileOptions {
+	conf, err := ttail.DefaultConfig()
+	if err != nil {
+		log.Debug("[auto]: load config", zap.Error(err))
+		return nil
+	}
+	name, confidence, err := ttail.DetectLogType(io.NewSectionReader(src, 0, size), conf)
+	if err != nil {
+		log.Debug("[auto]: type is not detected", zap.String("logname", fname), zap.Error(err))
+		return nil
+	}
+	log.Debug("[auto]: detected", zap.String("logname", fname), zap.String("type", name), zap.Float64("confidence", confidence))
+	rememberLogType(fname, name)
+	opts, err := ttail.OptionsFromConfig(name)
+	if err != nil {
+		log.Error("[auto]: detected type", zap.String("logname", fname), zap.Error(err))
+	}
+	return opts
+}
+
+// rememberLogType save detected type of file
+func rememberLogType(fname, name string) {
+	detected.Lock()
+	detected.types[fname] = name
+	detected.Unlock()
+}
+
+// fileLogType return detected type of file for auto type
+func fileLogType(fname, logType string) string {
+	if logType != autoLogType {
+		return logType
+	}
+	detected.Lock()
+	defer detected.Unlock()
+	return detected.types[fname]
+}
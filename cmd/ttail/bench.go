@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sakateka/ttail"
+)
+
+var (
+	flagBenchRuns    int
+	flagBenchBufSize string
+)
+
+var benchCommand = &command{
+	name: "bench",
+	args: "file [file ...]",
+	help: "measure time search and copy of time window to tune bufSize and stepsLimit",
+	flags: func(fs *flag.FlagSet) {
+		windowFlags(fs)
+		untilFlag(fs)
+		fs.IntVar(&flagBenchRuns, "runs", 5, "number of time searches per file and buffer size")
+		fs.StringVar(&flagBenchBufSize, "bufsize", "4096,16384,65536", "comma separated buffer sizes to measure")
+	},
+	run: runBench,
+}
+
+// benchResult of time search and copy with one buffer size
+type benchResult struct {
+	search    time.Duration
+	probes    int
+	readBytes int64
+	copyBytes int64
+	copy      time.Duration
+}
+
+// benchFile measure time search and copy of time window in file
+func benchFile(fname string, opts []ttail.TimeFileOptions) (benchResult, error) {
+	var res benchResult
+	for i := 0; i < flagBenchRuns; i++ {
+		start := time.Now()
+		src, tfile, err := openTimeSource(fname, flagLogType, opts)
+		if err != nil && err != io.EOF {
+			return res, err
+		}
+		res.search += time.Since(start)
+		stats := tfile.Stats()
+		res.probes += stats.Probes
+		res.readBytes += stats.BytesRead
+		if i == flagBenchRuns-1 && err == nil {
+			start = time.Now()
+			res.copyBytes, err = tfile.CopyTo(ioutil.Discard)
+			res.copy = time.Since(start)
+		}
+		src.Close()
+		if err != nil && err != io.EOF {
+			return res, err
+		}
+	}
+	runs := int64(flagBenchRuns)
+	res.search /= time.Duration(runs)
+	res.probes /= flagBenchRuns
+	res.readBytes /= runs
+	return res, nil
+}
+
+func runBench(fs *flag.FlagSet) error {
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if flagBenchRuns < 1 {
+		return fmt.Errorf("runs must be positive: %d", flagBenchRuns)
+	}
+	var sizes []int64
+	for _, arg := range strings.Split(flagBenchBufSize, ",") {
+		size, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("bad buffer size: %s", arg)
+		}
+		sizes = append(sizes, size)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tBUFSIZE\tSEARCH\tPROBES\tREAD\tCOPIED\tCOPY MB/s")
+	for _, fname := range fs.Args() {
+		for _, size := range sizes {
+			opts := append(windowOptions(flagDuration), ttail.WithBufSize(size))
+			res, err := benchFile(fname, opts)
+			if err != nil {
+				return fmt.Errorf("%s: %v", fname, err)
+			}
+			throughput := 0.0
+			if res.copy > 0 {
+				throughput = float64(res.copyBytes) / res.copy.Seconds() / (1 << 20)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%d\t%.1f\n",
+				fname, size, res.search, res.probes, res.readBytes, res.copyBytes, throughput)
+		}
+	}
+	return w.Flush()
+}
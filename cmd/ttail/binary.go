@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// binarySniffSize is a size of head of file checked for binary data
+const binarySniffSize = 4 << 10
+
+// errBinaryFile returned by openSource for files which are not text logs
+var errBinaryFile = errors.New("binary file is skipped")
+
+// binaryHead report whether head of file looks like binary data:
+// more than 1% of NUL bytes or more than 10% of control characters,
+// tabs, line ends and escape of terminal colors are text
+func binaryHead(head []byte) bool {
+	if len(head) == 0 {
+		return false
+	}
+	var nul, ctrl int
+	for _, c := range head {
+		switch {
+		case c == 0:
+			nul++
+		case c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f' && c != '\v' && c != 0x1b, c == 0x7f:
+			ctrl++
+		}
+	}
+	return nul*100 > len(head) || (nul+ctrl)*10 > len(head)
+}
+
+// binaryFile report whether local file looks like binary data,
+// missing or unreadable file is not binary
+func binaryFile(name string) bool {
+	file, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	head := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false
+	}
+	return binaryHead(head[:n])
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/sakateka/ttail"
+	"go.uber.org/zap"
+)
+
+var catCommand = &command{
+	name: "cat",
+	args: "file|url[:duration] [file|url[:duration] ...]",
+	help: "copy time window of files to stdout",
+	flags: func(fs *flag.FlagSet) {
+		windowFlags(fs)
+		untilFlag(fs)
+		outputFlags(fs)
+		journalFlags(fs)
+		cursorFlags(fs)
+		replayFlags(fs)
+		fs.StringVar(&flagLevels, "levels", "", "print counts of lines by level per file instead of lines: text or json")
+		fs.DurationVar(&flagGaps, "gaps", 0, "print gaps between lines longer than duration instead of lines: file, from, to, gap")
+		fs.StringVar(&flagHead, "head", "", "copy only first N lines or duration of time window, from the file start if -since is not set")
+		fs.DurationVar(&flagSplit, "split", 0, "write lines into files of time buckets of duration in current directory instead of stdout, e.g. 10m writes app-1030.log, app-1040.log for app.log")
+		fs.StringVar(&flagSplitLayout, "split-layout", "1504", "go time layout of bucket in -split file names, e.g. 20060102-1504 for windows longer than a day")
+		fs.DurationVar(&flagCompare, "compare", 0, "print difference of level counts, line rates and new errors between time window and the same window duration earlier, e.g. 24h")
+	},
+	run: runCat,
+}
+
+func runCat(fs *flag.FlagSet) error {
+	if fs.NArg() == 0 && !journalSelected() {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if flagLevels != "" && flagLevels != "text" && flagLevels != "json" {
+		return errors.New("bad levels format, want text or json: " + flagLevels)
+	}
+	if flagCompare > 0 {
+		return runCompare(fs.Args())
+	}
+	if journalSelected() {
+		if err := copyJournal(flagDuration, false); err != nil {
+			return err
+		}
+	}
+
+	var cursors *cursorFile
+	if flagCursorFile != "" {
+		var err error
+		if cursors, err = loadCursors(flagCursorFile); err != nil {
+			return err
+		}
+	}
+
+	for _, arg := range fs.Args() {
+		if err := copyWindow(arg, cursors); err != nil {
+			return err
+		}
+	}
+	if cursors != nil {
+		if err := cursors.save(); err != nil {
+			return err
+		}
+	}
+	switch flagLevels {
+	case "":
+	case "json":
+		return summary.writeJSON(os.Stdout)
+	default:
+		return summary.writeText(os.Stdout)
+	}
+	return nil
+}
+
+// copyWindow copy time window of file[:duration] argument to output,
+// errors of file are reported and only output errors are returned
+func copyWindow(arg string, cursors *cursorFile) error {
+	fname, duration := splitFileDuration(arg)
+	if duration == 0 {
+		duration = flagDuration
+	}
+	log.Debug("[cat]: process file", zap.String("fileName", fname), zap.Duration("duration", duration))
+
+	var opts []ttail.TimeFileOptions
+	bar := newProgressBar(fname)
+	if bar != nil {
+		opts = append(opts, ttail.WithProgress(bar.update))
+	}
+	src, tfile, err := openTimeFile(fname, duration, opts...)
+	if err != nil && (err != io.EOF || cursors == nil) {
+		if err != io.EOF {
+			fileError(openErrorKind(err), fname, err)
+		} else {
+			log.Debug("[cat]: findPosition got EOF")
+			src.Close()
+		}
+		return nil
+	}
+	searchWarnings(fname, tfile)
+	if cursors != nil && !cursors.resume(fname, src, tfile) && err == io.EOF {
+		// no lines in time window, next run starts from the end
+		tfile.SetOffset(tfile.Size())
+	}
+	w, err := newWindowWriter(fname, tfile, src)
+	if err != nil {
+		src.Close()
+		return err
+	}
+	if cursors != nil {
+		err = cursors.copy(fname, src, tfile, w, bar)
+	} else {
+		_, err = tfile.CopyTo(w)
+	}
+	bar.done()
+	if err != nil && err != errWindowEnd {
+		fileError("copy", fname, err)
+	}
+	if err := closeWindow(w); err != nil {
+		fileError("write", fname, err)
+	}
+	src.Close()
+	return nil
+}
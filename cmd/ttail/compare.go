@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sakateka/ttail"
+)
+
+// compareErrorsShown is a number of printed new error signatures
+const compareErrorsShown = 10
+
+var flagCompare time.Duration
+
+// signatureRe match variable parts of error lines: numbers, ids and hashes
+var signatureRe = regexp.MustCompile(`\b(?:0x)?[0-9a-fA-F]*[0-9][0-9a-fA-F]*\b`)
+
+// windowStats is a summary of lines of time window
+type windowStats struct {
+	lines  int
+	last   time.Time
+	levels map[string]int
+	// errors count ERROR and FATAL lines by signature, examples keep their first lines
+	errors   map[string]int
+	examples map[string]string
+}
+
+// errorSignature return error line without time and variable parts
+func errorSignature(tfile *ttail.TFile, line string) string {
+	if idx := tfile.TimeIndex([]byte(line)); idx != nil {
+		line = line[:idx[0]] + line[idx[1]:]
+	}
+	return signatureRe.ReplaceAllString(line, "#")
+}
+
+// collectWindow summarize lines of file window found by opts until end,
+// zero end means the end of file
+func collectWindow(fname string, opts []ttail.TimeFileOptions, end time.Time) (*windowStats, error) {
+	stats := &windowStats{levels: map[string]int{}, errors: map[string]int{}, examples: map[string]string{}}
+	src, tfile, err := openTimeSource(fname, flagLogType, opts)
+	if err == io.EOF {
+		src.Close()
+		return stats, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	records := tfile.Records()
+	for records.Next() {
+		r := records.Record()
+		if !end.IsZero() && r.Time.After(end) {
+			break
+		}
+		stats.lines++
+		stats.last = r.Time
+		level := r.Level
+		if level == "" {
+			level = "NONE"
+		}
+		stats.levels[level]++
+		if level == "ERROR" || level == "FATAL" {
+			sig := errorSignature(tfile, r.RawLine)
+			if stats.errors[sig] == 0 {
+				stats.examples[sig] = r.RawLine
+			}
+			stats.errors[sig]++
+		}
+	}
+	return stats, records.Err()
+}
+
+// compareWindow summarize time window of file[:duration] argument and
+// the same window flagCompare earlier and print their difference
+func compareWindow(w io.Writer, arg string) error {
+	fname, duration := splitFileDuration(arg)
+	if duration == 0 {
+		duration = flagDuration
+	}
+	end := now()
+	if flagTimeFromLastLine {
+		// window ends at the last line, it is known after reading
+		end = time.Time{}
+	}
+	base, err := collectWindow(fname, windowOptions(duration), end)
+	if err != nil {
+		return err
+	}
+	if end.IsZero() {
+		end = base.last
+	}
+	if end.IsZero() {
+		return errors.New("time of window end is not found")
+	}
+	refEnd := end.Add(-flagCompare)
+	window := duration
+	opts := append(windowOptions(duration),
+		ttail.WithTimeFromLastLine(false),
+		ttail.WithClock(func() time.Time { return refEnd }),
+	)
+	if flagSince != "" {
+		since, err := parseTimeArg(flagSince)
+		if err != nil {
+			return err
+		}
+		window = end.Sub(since)
+		opts = append(opts, ttail.WithSince(since.Add(-flagCompare)))
+	}
+	ref, err := collectWindow(fname, opts, refEnd)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "FILE\t%s\n", fname)
+	fmt.Fprintf(tw, "WINDOW END\t%s\t%s\n", end.Format(time.RFC3339), refEnd.Format(time.RFC3339))
+	fmt.Fprintf(tw, "\tNOW\t%s AGO\tCHANGE\n", flagCompare)
+	fmt.Fprintf(tw, "lines\t%d\t%d\t%s\n", base.lines, ref.lines, change(float64(base.lines), float64(ref.lines)))
+	if minutes := window.Minutes(); minutes > 0 {
+		rate, prev := float64(base.lines)/minutes, float64(ref.lines)/minutes
+		fmt.Fprintf(tw, "lines/min\t%.1f\t%.1f\t%s\n", rate, prev, change(rate, prev))
+	}
+	for _, level := range levels {
+		if base.levels[level] == 0 && ref.levels[level] == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", level, base.levels[level], ref.levels[level],
+			change(float64(base.levels[level]), float64(ref.levels[level])))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var fresh []string
+	for sig := range base.errors {
+		if ref.errors[sig] == 0 {
+			fresh = append(fresh, sig)
+		}
+	}
+	sort.Slice(fresh, func(i, j int) bool {
+		if base.errors[fresh[i]] != base.errors[fresh[j]] {
+			return base.errors[fresh[i]] > base.errors[fresh[j]]
+		}
+		return fresh[i] < fresh[j]
+	})
+	if len(fresh) > 0 {
+		fmt.Fprintf(w, "new errors: %d\n", len(fresh))
+	}
+	for i, sig := range fresh {
+		if i == compareErrorsShown {
+			fmt.Fprintf(w, "  ... %d more\n", len(fresh)-i)
+			break
+		}
+		fmt.Fprintf(w, "  %d\t%s\n", base.errors[sig], base.examples[sig])
+	}
+	_, err = fmt.Fprintln(w)
+	return err
+}
+
+// change of value relative to previous one in percents
+func change(value, prev float64) string {
+	switch {
+	case value == prev:
+		return "0%"
+	case prev == 0:
+		return "new"
+	}
+	return fmt.Sprintf("%+.1f%%", (value-prev)/prev*100)
+}
+
+// runCompare print comparison of windows of files
+func runCompare(args []string) error {
+	for _, arg := range args {
+		if err := compareWindow(os.Stdout, arg); err != nil {
+			fileError("compare", arg, err)
+		}
+	}
+	return nil
+}
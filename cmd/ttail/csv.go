@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sakateka/ttail"
+)
+
+var (
+	flagOutput  string
+	flagColumns string
+)
+
+// fieldsFilter fill fields of record by parser of log type
+func fieldsFilter(parse ttail.FieldParser) filter {
+	return func(r *record) bool {
+		r.Fields = parse(r.Line)
+		return true
+	}
+}
+
+// csvOutput write selected fields of records as csv,
+// @time, @file and @line columns are time, file and whole line of record
+type csvOutput struct {
+	w       *csv.Writer
+	columns []string
+	row     []string
+}
+
+func newCSVOutput(w io.Writer, columns string) *csvOutput {
+	o := &csvOutput{w: csv.NewWriter(w)}
+	if columns != "" {
+		o.columns = strings.Split(columns, ",")
+	}
+	return o
+}
+
+func (o *csvOutput) write(r *record) error {
+	if o.row == nil {
+		if o.columns == nil {
+			// columns of the first record
+			o.columns = append(o.columns, "@time")
+			var names []string
+			for name := range r.Fields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if len(names) == 0 {
+				names = append(names, "@line")
+			}
+			o.columns = append(o.columns, names...)
+		}
+		if err := o.w.Write(o.columns); err != nil {
+			return err
+		}
+		o.row = make([]string, len(o.columns))
+	}
+	for i, column := range o.columns {
+		switch column {
+		case "@time":
+			o.row[i] = ""
+			if !r.Time.IsZero() {
+				o.row[i] = r.Time.Format(time.RFC3339Nano)
+			}
+		case "@file":
+			o.row[i] = r.File
+		case "@line":
+			o.row[i] = r.Line
+		default:
+			o.row[i] = r.Fields[column]
+		}
+	}
+	return o.w.Write(o.row)
+}
+
+func (o *csvOutput) flush() error {
+	o.w.Flush()
+	return o.w.Error()
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sakateka/ttail"
+	"go.uber.org/zap"
+)
+
+var flagCursorFile string
+
+func cursorFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagCursorFile, "cursor-file", "", "continue files from offsets saved by previous run to this file")
+}
+
+// cursor is a position in file where previous run stopped
+type cursor struct {
+	Offset int64     `json:"offset"`
+	Time   time.Time `json:"time,omitempty"`
+	Dev    uint64    `json:"dev,omitempty"`
+	Inode  uint64    `json:"inode,omitempty"`
+	// Head is a fingerprint of the first line of file
+	Head *ttail.Fingerprint `json:"head,omitempty"`
+}
+
+// cursorFile keep cursors of files by name
+type cursorFile struct {
+	path    string
+	cursors map[string]*cursor
+}
+
+func loadCursors(path string) (*cursorFile, error) {
+	c := &cursorFile{path: path, cursors: map[string]*cursor{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.cursors); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// save cursors atomically
+func (c *cursorFile) save() error {
+	data, err := json.MarshalIndent(c.cursors, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// key of file in cursors
+func cursorKey(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		if _, err := os.Stat(name); err == nil {
+			return abs
+		}
+	}
+	return name
+}
+
+// identify return device and inode of local file source
+func identify(src source) (dev, inode uint64) {
+	if file, ok := src.(*os.File); ok {
+		if info, err := file.Stat(); err == nil {
+			return fileID(info)
+		}
+	}
+	return 0, 0
+}
+
+// resume move start of time window to the saved cursor, rotated,
+// truncated or rewritten in place file is read from the beginning
+func (c *cursorFile) resume(name string, src source, tfile *ttail.TFile) bool {
+	cur, ok := c.cursors[cursorKey(name)]
+	if !ok {
+		return false
+	}
+	dev, inode := identify(src)
+	same := true
+	if cur.Head != nil {
+		var err error
+		if same, err = cur.Head.Match(src); err != nil {
+			log.Debug("[cursor]: match fingerprint", zap.String("logname", name), zap.Error(err))
+			same = true
+		}
+	}
+	switch {
+	case cur.Dev != dev || cur.Inode != inode:
+		log.Debug("[cursor]: file rotated, read from the beginning", zap.String("logname", name))
+		tfile.SetOffset(0)
+	case cur.Offset > tfile.Size():
+		log.Debug("[cursor]: file truncated, read from the beginning", zap.String("logname", name))
+		tfile.SetOffset(0)
+	case !same:
+		// truncated file grew over cursor or inode is reused by new file
+		log.Debug("[cursor]: file rewritten, read from the beginning", zap.String("logname", name))
+		tfile.SetOffset(0)
+	default:
+		log.Debug("[cursor]: resume", zap.String("logname", name), zap.Int64("offset", cur.Offset))
+		tfile.SetOffset(cur.Offset)
+	}
+	return true
+}
+
+// copy time window of complete lines and update cursor of file, lines are
+// filtered here instead of CopyTo, so the cursor is advanced over dropped lines
+func (c *cursorFile) copy(name string, src source, tfile *ttail.TFile, w io.Writer, bar *progressBar) error {
+	start := tfile.Offset()
+	r, err := tfile.GetReader()
+	if err != nil {
+		return err
+	}
+	if sr, ok := r.(*io.SectionReader); ok && bar != nil {
+		r = &progressReader{r: sr, total: sr.Size(), bar: bar}
+	}
+	lines := &completeLines{w: w, filter: tfile.FilterLines}
+	_, err = io.Copy(lines, r)
+
+	key := cursorKey(name)
+	cur, ok := c.cursors[key]
+	if !ok {
+		cur = &cursor{}
+		c.cursors[key] = cur
+	}
+	cur.Offset = start + lines.consumed
+	cur.Dev, cur.Inode = identify(src)
+	if head, herr := ttail.NewFingerprint(src); herr == nil {
+		cur.Head = &head
+	}
+	if tm, perr := tfile.ParseTime(lines.last); perr == nil {
+		cur.Time = tm
+	}
+	return err
+}
+
+// completeLines pass only complete lines, so incomplete last line
+// will be read by the next run when it is written to the end,
+// consumed counts source bytes of written and dropped lines
+type completeLines struct {
+	w        io.Writer
+	filter   func([]byte) ([]byte, error)
+	consumed int64
+	pending  []byte
+	last     []byte
+}
+
+func (c *completeLines) Write(p []byte) (int, error) {
+	c.pending = append(c.pending, p...)
+	idx := bytes.LastIndexByte(c.pending, '\n')
+	if idx < 0 {
+		return len(p), nil
+	}
+	if err := c.writeLines(c.pending[:idx+1]); err != nil {
+		return 0, err
+	}
+	c.pending = append(c.pending[:0], c.pending[idx+1:]...)
+	return len(p), nil
+}
+
+// writeLines write complete lines kept by filter, runs of kept lines are written at once
+func (c *completeLines) writeLines(lines []byte) error {
+	start := 0
+	for pos := 0; pos < len(lines); {
+		end := pos + bytes.IndexByte(lines[pos:], '\n') + 1
+		kept, err := c.filter(lines[pos:end])
+		if len(kept) == 0 {
+			if werr := c.writeRun(lines[start:pos]); werr != nil {
+				return werr
+			}
+			if err != nil {
+				return err
+			}
+			c.consumed += int64(end - pos)
+			start = end
+		}
+		pos = end
+	}
+	return c.writeRun(lines[start:])
+}
+
+// writeRun write run of kept lines, only written lines are consumed
+// if writer stops at the end of time window
+func (c *completeLines) writeRun(run []byte) error {
+	if len(run) == 0 {
+		return nil
+	}
+	n, err := c.w.Write(run)
+	c.consumed += int64(n)
+	if idx := bytes.LastIndexByte(run[:n], '\n'); idx >= 0 {
+		start := bytes.LastIndexByte(run[:idx], '\n') + 1
+		c.last = append(c.last[:0], run[start:idx]...)
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sakateka/ttail"
+)
+
+var flagDedup bool
+
+// dedupOutput fold runs of lines equal except time into the first line
+// and "last message repeated N times" line, like syslog does
+type dedupOutput struct {
+	out   output
+	tfile *ttail.TFile
+	key   string
+	last  record
+	count int
+}
+
+// dedupKey return line without time
+func (o *dedupOutput) dedupKey(line string) string {
+	idx := o.tfile.TimeIndex([]byte(line))
+	if idx == nil {
+		return line
+	}
+	return line[:idx[0]] + line[idx[1]:]
+}
+
+func (o *dedupOutput) write(r *record) error {
+	key := o.dedupKey(r.Line)
+	if o.last.Line != "" && key == o.key {
+		o.count++
+		o.last.Time = r.Time
+		return nil
+	}
+	if err := o.repeated(); err != nil {
+		return err
+	}
+	o.key, o.last = key, *r
+	return o.out.write(r)
+}
+
+// repeated write annotation of folded lines
+func (o *dedupOutput) repeated() error {
+	if o.count == 0 {
+		return nil
+	}
+	r := o.last
+	r.Line = fmt.Sprintf("last message repeated %d times", o.count)
+	o.count = 0
+	return o.out.write(&r)
+}
+
+func (o *dedupOutput) flush() error {
+	if err := o.repeated(); err != nil {
+		return err
+	}
+	if f, ok := o.out.(flusher); ok {
+		return f.flush()
+	}
+	return nil
+}
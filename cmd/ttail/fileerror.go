@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sakateka/ttail"
+	"go.uber.org/zap"
+)
+
+var (
+	flagErrorFormat string
+	// commandName is a name of running command
+	commandName string
+)
+
+// fileErrorRecord is a json line of per file error on stderr
+type fileErrorRecord struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	File    string    `json:"file"`
+	Kind    string    `json:"kind"`
+	Error   string    `json:"error"`
+}
+
+// fileError report error of file, kind is a short machine readable
+// class of error: stat, search, binary, no-timestamp, steps-exceeded, copy, ...
+func fileError(kind, fname string, err error) {
+	if flagErrorFormat != "json" {
+		log.Error("["+commandName+"]: "+kind, zap.String("logname", fname), zap.Error(err))
+		return
+	}
+	_ = json.NewEncoder(os.Stderr).Encode(fileErrorRecord{
+		Time:    time.Now(),
+		Command: commandName,
+		File:    fname,
+		Kind:    kind,
+		Error:   err.Error(),
+	})
+}
+
+// openErrorKind classify error of openTimeFile
+func openErrorKind(err error) string {
+	if err == errBinaryFile {
+		return "binary"
+	}
+	if os.IsNotExist(err) || os.IsPermission(err) || strings.HasSuffix(err.Error(), "is a directory") {
+		return "stat"
+	}
+	return "search"
+}
+
+// searchWarnings report problems of time search which did not stop copy
+func searchWarnings(fname string, tfile *ttail.TFile) {
+	stats := tfile.Stats()
+	if stats.StepsExceeded {
+		fileError("steps-exceeded", fname, errors.New("time of last line is not found in steps limit, whole file is copied"))
+	}
+	if stats.NoTime {
+		fileError("no-timestamp", fname, errors.New("time is not found in file, whole file is copied"))
+	}
+}
@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "os"
+
+// fileID is not supported on windows, files are compared by size only
+func fileID(info os.FileInfo) (dev, inode uint64) {
+	return 0, 0
+}
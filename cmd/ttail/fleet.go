@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sakateka/ttail"
+	"go.uber.org/zap"
+)
+
+var (
+	flagHostsFile string
+	flagFleetJobs int
+)
+
+var fleetCommand = &command{
+	name: "fleet",
+	args: "-H hosts.txt -- [query options] /path [/path ...]",
+	help: "copy time window of files on many hosts over ssh merged by time with host prefixes",
+	flags: func(fs *flag.FlagSet) {
+		windowFlags(fs)
+		untilFlag(fs)
+		outputFlags(fs)
+		fs.StringVar(&flagHostsFile, "H", "", "file with ssh destinations [user@]host, one per line")
+		fs.IntVar(&flagFleetJobs, "j", 16, "number of hosts searched concurrently")
+	},
+	run: runFleet,
+}
+
+// readHosts return hosts of file, empty lines and # comments are skipped
+func readHosts(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var hosts []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			hosts = append(hosts, line)
+		}
+	}
+	return hosts, scanner.Err()
+}
+
+// fleetStream is a time window of remote file
+type fleetStream struct {
+	name    string
+	src     source
+	records *ttail.Records
+}
+
+func (s *fleetStream) next() (*record, error) {
+	if !s.records.Next() {
+		// error of one host does not stop others
+		if err := s.records.Err(); err != nil {
+			fileError("copy", s.name, err)
+		}
+		s.src.Close()
+		return nil, io.EOF
+	}
+	r := s.records.Record()
+	return &record{File: s.name, Time: r.Time, Line: r.RawLine}, nil
+}
+
+// openFleet search time windows of remote files concurrently,
+// errors of files are reported and their streams are skipped
+func openFleet(hosts, paths []string) []recordStream {
+	type job struct {
+		i    int
+		name string
+		arg  string
+	}
+	var jobs []job
+	for _, host := range hosts {
+		for _, path := range paths {
+			name := host
+			if len(paths) > 1 {
+				name = host + ":" + path
+			}
+			jobs = append(jobs, job{i: len(jobs), name: name, arg: host + ":" + path})
+		}
+	}
+
+	streams := make([]recordStream, len(jobs))
+	sem := make(chan struct{}, flagFleetJobs)
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(j job) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fname, duration := splitFileDuration(j.arg)
+			if duration == 0 {
+				duration = flagDuration
+			}
+			log.Debug("[fleet]: search", zap.String("fileName", fname))
+			src, tfile, err := openTimeFile(fname, duration)
+			if err == io.EOF {
+				src.Close()
+				return
+			} else if err != nil {
+				fileError(openErrorKind(err), j.arg, err)
+				return
+			}
+			streams[j.i] = &fleetStream{name: j.name, src: src, records: tfile.Records()}
+		}(j)
+	}
+	wg.Wait()
+
+	opened := streams[:0]
+	for _, s := range streams {
+		if s != nil {
+			opened = append(opened, s)
+		}
+	}
+	return opened
+}
+
+func runFleet(fs *flag.FlagSet) error {
+	// query options follow "--"
+	if err := fs.Parse(fs.Args()); err != nil {
+		return err
+	}
+	if err := fixNow(); err != nil {
+		return err
+	}
+	if flagHostsFile == "" || fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if flagFleetJobs < 1 {
+		return errors.New("-j must be positive")
+	}
+	hosts, err := readHosts(flagHostsFile)
+	if err != nil {
+		return err
+	}
+	streams := openFleet(hosts, fs.Args())
+
+	// tfile is used only to parse time of lines by type of query
+	tfile := ttail.NewTimeReader(nil, 0, windowOptions(flagDuration)...)
+	lw, err := newLineWriter(os.Stdout, "", tfile, "{{.File}} {{.Line}}")
+	if err != nil {
+		return err
+	}
+	redact, err := redactFilter(flagLogType)
+	if err != nil {
+		return err
+	}
+	if redact != nil {
+		lw.filters = append(lw.filters, redact)
+	}
+	if err := mergeRecords(streams, lw.writeRecord); err != nil {
+		return err
+	}
+	return closeWindow(lw)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sakateka/ttail"
+	"github.com/sakateka/ttail/multitail"
+	"go.uber.org/zap"
+)
+
+var flagFollowInterval time.Duration
+
+var followCommand = &command{
+	name: "follow",
+	args: "file[:duration] [file[:duration] ...]",
+	help: "copy time window of files and wait for appended lines",
+	flags: func(fs *flag.FlagSet) {
+		windowFlags(fs)
+		outputFlags(fs)
+		journalFlags(fs)
+		metricsFlags(fs)
+		fs.DurationVar(&flagFollowInterval, "i", time.Second, "poll interval for appended data")
+	},
+	run: runFollow,
+}
+
+func runFollow(fs *flag.FlagSet) error {
+	if fs.NArg() == 0 && !journalSelected() {
+		fs.Usage()
+		os.Exit(1)
+	}
+	startMetrics()
+	if journalSelected() {
+		if fs.NArg() > 0 {
+			return errors.New("journal and files can't be followed together")
+		}
+		return copyJournal(flagDuration, true)
+	}
+
+	var opts []multitail.Option
+	opts = append(opts, multitail.WithInterval(flagFollowInterval))
+	if flagLogType == autoLogType {
+		conf, err := ttail.DefaultConfig()
+		if err != nil {
+			return err
+		}
+		opts = append(opts, multitail.WithConfig(conf))
+	}
+	tailer := multitail.New(opts...)
+	for _, arg := range fs.Args() {
+		fname, duration := splitFileDuration(arg)
+		if duration == 0 {
+			duration = flagDuration
+		}
+		if _, _, remote := splitSSHName(fname); remote || strings.Contains(fname, "://") {
+			fileError("unsupported", fname, errors.New("only local files can be followed"))
+			continue
+		}
+		if binaryFile(fname) {
+			fileError("binary", fname, errBinaryFile)
+			continue
+		}
+		log.Debug("[follow]: process file", zap.String("fileName", fname), zap.Duration("duration", duration))
+
+		fileOpts := windowOptions(duration)
+		if opt := loadIndex(fname); opt != nil {
+			fileOpts = append(fileOpts, opt)
+		}
+		tailer.Add(fname, fileOpts...)
+	}
+	go tailer.Run()
+
+	// missing files and files with errors are opened again by tailer
+	writers := map[string]io.Writer{}
+	tfiles := map[string]*ttail.TFile{}
+	for ev := range tailer.Events() {
+		switch ev.Kind {
+		case multitail.Opened:
+			if ev.Type != "" {
+				rememberLogType(ev.File, ev.Type)
+			}
+			observeSearch(fileLogType(ev.File, flagLogType), ev.TFile)
+			searchWarnings(ev.File, ev.TFile)
+			w, err := newWindowWriter(ev.File, ev.TFile, nil)
+			if err != nil {
+				tailer.Close()
+				return err
+			}
+			writers[ev.File] = w
+			tfiles[ev.File] = ev.TFile
+		case multitail.Lines:
+			w := writers[ev.File]
+			// lines of policy stop before line without time are written
+			data, err := tfiles[ev.File].FilterLines(ev.Data)
+			if err != nil {
+				fileError("no-timestamp", ev.File, err)
+			}
+			if _, err := w.Write(data); err != nil {
+				fileError("write", ev.File, err)
+			} else if err := flushWindow(w); err != nil {
+				fileError("write", ev.File, err)
+			}
+		case multitail.Error:
+			fileError(openErrorKind(ev.Err), ev.File, ev.Err)
+		default:
+			log.Debug("[follow]: file "+ev.Kind.String(), zap.String("logname", ev.File))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"text/template"
+)
+
+var flagFormat string
+
+// templateOutput write records formatted by go template
+type templateOutput struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+func newTemplateOutput(w io.Writer, format string) (*templateOutput, error) {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	tmpl, err := template.New("format").Option("missingkey=zero").Parse(format)
+	if err != nil {
+		return nil, err
+	}
+	return &templateOutput{w: w, tmpl: tmpl}, nil
+}
+
+func (o *templateOutput) write(r *record) error {
+	return o.tmpl.Execute(o.w, r)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+var flagGaps time.Duration
+
+// gapsOutput report gaps between times of consecutive lines
+// longer than threshold instead of writing lines
+type gapsOutput struct {
+	w         io.Writer
+	threshold time.Duration
+	last      time.Time
+}
+
+func (o *gapsOutput) write(r *record) error {
+	if r.Time.IsZero() {
+		return nil
+	}
+	if !o.last.IsZero() {
+		if gap := r.Time.Sub(o.last); gap > o.threshold {
+			_, err := fmt.Fprintf(o.w, "%s\t%s\t%s\t%s\n", r.File,
+				o.last.Format(time.RFC3339Nano), r.Time.Format(time.RFC3339Nano), gap)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	o.last = r.Time
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	flagHead  string
+	flagSince string
+	flagUntil string
+)
+
+// parseHead parse -head value as number of lines or duration
+func parseHead(arg string) (time.Duration, int, error) {
+	if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+		return 0, n, nil
+	}
+	if d, err := time.ParseDuration(arg); err == nil && d > 0 {
+		return d, 0, nil
+	}
+	return 0, 0, fmt.Errorf("bad head, want number of lines or duration: %s", arg)
+}
+
+// headFromStart report whether -head is a number of the first lines of file
+// without -since and -until, they are copied from the start without time search,
+// so lines are counted even if they have no time
+func headFromStart() bool {
+	if flagHead == "" || flagSince != "" || flagUntil != "" {
+		return false
+	}
+	_, lines, err := parseHead(flagHead)
+	return err == nil && lines > 0
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sakateka/ttail"
+	"go.uber.org/zap"
+)
+
+var (
+	flagGranularity time.Duration
+	flagCompress    bool
+)
+
+var indexCommand = &command{
+	name: "index",
+	args: "file [file ...]",
+	help: "build " + ttail.IndexSuffix + " time index next to files to speed up time search",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&flagLogType, "t", "", "use a type of log, auto detects type by first lines of file (default tskv)")
+		fs.DurationVar(&flagGranularity, "granularity", time.Minute, "time between index entries")
+		fs.BoolVar(&flagCompress, "compress", false, "build "+ttail.GzipIndexSuffix+" seek index of .gz files written as many gzip members (e.g. by bgzip or pigz --independent), single member files are refused")
+	},
+	run: runIndex,
+}
+
+// loadIndex return option with index of local file if it exists
+// and it is built for the current content of file
+func loadIndex(fname string) ttail.TimeFileOptions {
+	file, err := os.Open(fname + ttail.IndexSuffix)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	idx, err := ttail.ReadIndex(file)
+	if err != nil {
+		log.Debug("[index]: skip index", zap.String("logname", fname), zap.Error(err))
+		return nil
+	}
+	logFile, err := os.Open(fname)
+	if err != nil {
+		return nil
+	}
+	defer logFile.Close()
+	if ok, err := idx.Match(logFile); err != nil || !ok {
+		log.Debug("[index]: skip stale index of rotated or rewritten file, rebuild it", zap.String("logname", fname), zap.Error(err))
+		return nil
+	}
+	log.Debug("[index]: use index", zap.String("logname", fname), zap.Int("entries", len(idx.Entries)))
+	return ttail.WithIndex(idx)
+}
+
+// writeIndex build index of file and write it atomically
+func writeIndex(fname string, opts []ttail.TimeFileOptions) (*ttail.Index, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if flagLogType == autoLogType {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		opts = detectLogType(fname, file, info.Size())
+	}
+	idx, err := ttail.NewTimeFile(file, opts...).BuildIndex(flagGranularity)
+	if err != nil {
+		return nil, err
+	}
+
+	return idx, writeAtomic(fname+ttail.IndexSuffix, idx)
+}
+
+// writeGzipIndex build seek index of gzip file and write it atomically,
+// index with members too large to be read by it is refused
+func writeGzipIndex(fname string) (*ttail.GzipIndex, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	idx, err := ttail.BuildGzipIndex(file)
+	if err != nil {
+		return nil, err
+	}
+	if size := idx.MaxMemberSize(); size > gzipMaxMember {
+		return nil, fmt.Errorf("gzip member of %d bytes is larger than %d, recompress it by smaller members", size, gzipMaxMember)
+	}
+	return idx, writeAtomic(fname+ttail.GzipIndexSuffix, idx)
+}
+
+// writeAtomic write data into temporary file and rename it to path
+func writeAtomic(path string, data io.WriterTo) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_ = tmp.Chmod(0644)
+	if _, err := data.WriteTo(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func runIndex(fs *flag.FlagSet) error {
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if flagGranularity <= 0 {
+		return fmt.Errorf("granularity must be positive: %s", flagGranularity)
+	}
+	var opts []ttail.TimeFileOptions
+	if flagLogType != "" && flagLogType != autoLogType {
+		logOpts, err := ttail.OptionsFromConfig(flagLogType)
+		if err != nil {
+			return err
+		}
+		opts = logOpts
+	}
+	for _, fname := range fs.Args() {
+		if flagCompress {
+			if !strings.HasSuffix(fname, ".gz") {
+				fileError("index", fname, errors.New("only .gz files have seek index"))
+				continue
+			}
+			idx, err := writeGzipIndex(fname)
+			if err != nil {
+				fileError("index", fname, err)
+				continue
+			}
+			fmt.Printf("%s%s: %d members\n", fname, ttail.GzipIndexSuffix, len(idx.Members))
+			continue
+		}
+		idx, err := writeIndex(fname, opts)
+		if err != nil {
+			fileError("index", fname, err)
+			continue
+		}
+		fmt.Printf("%s%s: %d entries\n", fname, ttail.IndexSuffix, len(idx.Entries))
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sakateka/ttail"
+	"go.uber.org/zap"
+)
+
+const (
+	readAheadMinChunk = 64 << 10 // 64kb
+	readAheadMaxChunk = 8 << 20  // 8mb
+
+	// gzipMaxMember is a size of the largest gzip member read by seek index,
+	// files with larger members are decompressed into temporary file
+	gzipMaxMember = 16 << 20 // 16mb
+)
+
+// source of log data for time search
+type source interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// typedSource has fixed log format
+type typedSource interface {
+	options() []ttail.TimeFileOptions
+}
+
+// filteredSource lines must be processed before output
+type filteredSource interface {
+	filter() filter
+}
+
+// openSource open local file or remote url, return source and its size,
+// gzipped sources are read by seek index or decompressed into temporary file,
+// window options limit output of commands, e.g. docker logs
+func openSource(name string, window ...ttail.TimeFileOptions) (source, int64, error) {
+	src, size, err := openRawSource(name, window)
+	if err == nil && strings.HasSuffix(name, ".gz") {
+		if gz := seekableGzip(name, src, size); gz != nil {
+			return gz, gz.Size(), nil
+		}
+		src, size, err = gunzipSource(src, size)
+	}
+	if err != nil {
+		return src, size, err
+	}
+	if _, ok := src.(typedSource); ok {
+		return src, size, nil
+	}
+	return sniffEventLog(src, size)
+}
+
+func openRawSource(name string, window []ttail.TimeFileOptions) (source, int64, error) {
+	if strings.HasPrefix(name, "docker://") {
+		return openDocker(strings.TrimPrefix(name, "docker://"), window)
+	}
+
+	var (
+		src *httpSource
+		err error
+	)
+	switch {
+	case strings.HasPrefix(name, "http://"), strings.HasPrefix(name, "https://"):
+		src, err = openHTTP(name, name, nil)
+	case strings.HasPrefix(name, "s3://"):
+		src, err = openS3(name)
+	case strings.HasPrefix(name, "gs://"):
+		src, err = openGS(name)
+	}
+	if err != nil {
+		return nil, 0, err
+	} else if src != nil {
+		return src, src.size, nil
+	}
+
+	if dest, path, ok := splitSSHName(name); ok {
+		src, err := openSSH(dest, path)
+		if err != nil {
+			return nil, 0, err
+		}
+		return src, src.size, nil
+	}
+
+	fileInfo, err := os.Stat(name)
+	if err != nil {
+		return nil, 0, err
+	} else if fileInfo.IsDir() {
+		return nil, 0, fmt.Errorf("%s is a directory", name)
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	return file, fileInfo.Size(), nil
+}
+
+// tempFile removed on close
+type tempFile struct {
+	*os.File
+}
+
+func (t tempFile) Close() error {
+	err := t.File.Close()
+	if rerr := os.Remove(t.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// gzipSource read gzip file by its seek index
+type gzipSource struct {
+	*ttail.GzipReaderAt
+	io.Closer
+}
+
+// seekableGzip return source of local gzip file with fresh seek index,
+// index of single member file is useless, it can't be read at random offset
+func seekableGzip(name string, src source, size int64) *gzipSource {
+	if _, ok := src.(*os.File); !ok {
+		return nil
+	}
+	file, err := os.Open(name + ttail.GzipIndexSuffix)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	idx, err := ttail.ReadGzipIndex(file)
+	if err != nil || idx.Size != size || len(idx.Members) < 2 || idx.MaxMemberSize() > gzipMaxMember {
+		log.Debug("[input]: skip gzip index", zap.String("logname", name), zap.Error(err))
+		return nil
+	}
+	log.Debug("[input]: use gzip index", zap.String("logname", name), zap.Int("members", len(idx.Members)))
+	return &gzipSource{ttail.NewGzipReaderAt(src, idx), src}
+}
+
+// gunzipSource decompress src into temporary file, because compressed
+// data can't be read at random offset without reading it from the beginning
+func gunzipSource(src source, size int64) (source, int64, error) {
+	defer src.Close()
+	zr, err := gzip.NewReader(io.NewSectionReader(src, 0, size))
+	if err != nil {
+		return nil, 0, err
+	}
+	tmp, err := os.CreateTemp("", "ttail-*.log")
+	if err != nil {
+		return nil, 0, err
+	}
+	file := tempFile{tmp}
+	n, err := io.Copy(file, zr)
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, n, nil
+}
+
+// readAhead implements io.ReaderAt for remote sources, random reads
+// (binary search) are fetched by small chunks and sequential reads
+// (copy of time window) by growing chunks
+type readAhead struct {
+	mu       sync.Mutex
+	size     int64
+	fetch    func(p []byte, off int64) (int, error)
+	chunk    int
+	cache    []byte
+	cacheOff int64
+}
+
+func newReadAhead(size int64, fetch func(p []byte, off int64) (int, error)) *readAhead {
+	return &readAhead{
+		size:  size,
+		fetch: fetch,
+		chunk: readAheadMinChunk,
+	}
+}
+
+// ReadAt implements io.ReaderAt
+func (r *readAhead) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for n < len(p) && off < r.size {
+		if off >= r.cacheOff && off < r.cacheOff+int64(len(r.cache)) {
+			c := copy(p[n:], r.cache[off-r.cacheOff:])
+			n += c
+			off += int64(c)
+			continue
+		}
+		if off == r.cacheOff+int64(len(r.cache)) {
+			if r.chunk *= 2; r.chunk > readAheadMaxChunk {
+				r.chunk = readAheadMaxChunk
+			}
+		} else {
+			r.chunk = readAheadMinChunk
+		}
+		size := len(p) - n
+		if size < r.chunk {
+			size = r.chunk
+		}
+		if rest := r.size - off; int64(size) > rest {
+			size = int(rest)
+		}
+		if cap(r.cache) < size {
+			r.cache = make([]byte, size)
+		}
+		got, err := r.fetch(r.cache[:size], off)
+		r.cache = r.cache[:got]
+		r.cacheOff = off
+		if got == 0 {
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			return n, err
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sakateka/ttail"
+	"go.uber.org/zap"
+)
+
+// dockerTimeRe match time of docker json-file log line
+const dockerTimeRe = `"time":"([^"]+)"`
+
+// dockerSource is a log of docker container, json-file log of container
+// is searched directly, if it is not readable (e.g. not root) output
+// of docker logs is used
+type dockerSource struct {
+	source
+	name string
+	json bool
+}
+
+func openDocker(container string, window []ttail.TimeFileOptions) (source, int64, error) {
+	name := "docker://" + container
+	out, err := exec.Command("docker", "inspect", "--format", "{{.LogPath}}", container).Output()
+	if err != nil {
+		return nil, 0, err
+	}
+	if path := strings.TrimSpace(string(out)); path != "" {
+		file, err := os.Open(path)
+		if err == nil {
+			info, err := file.Stat()
+			if err != nil {
+				file.Close()
+				return nil, 0, err
+			}
+			// json-file driver or CRI format of containerd
+			var first [1]byte
+			_, _ = file.ReadAt(first[:], 0)
+			return &dockerSource{source: file, name: name, json: first[0] == '{'}, info.Size(), nil
+		}
+		log.Debug("[docker]: log file is not readable, use docker logs", zap.String("path", path), zap.Error(err))
+	}
+
+	tmp, err := os.CreateTemp("", "ttail-*.log")
+	if err != nil {
+		return nil, 0, err
+	}
+	file := tempFile{tmp}
+	cmd := exec.Command("docker", dockerLogsArgs(container, window)...)
+	cmd.Stdout = file
+	cmd.Stderr = file
+	if err := cmd.Run(); err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return &dockerSource{source: file, name: name}, info.Size(), nil
+}
+
+// dockerLogsArgs return docker logs arguments limited by time window,
+// bounds are rounded to seconds outwards and exact window is searched after
+func dockerLogsArgs(container string, window []ttail.TimeFileOptions) []string {
+	args := []string{"logs", "--timestamps"}
+	since, until := ttail.TimeWindow(window...)
+	if !since.IsZero() {
+		args = append(args, "--since", strconv.FormatInt(since.Unix(), 10))
+	}
+	if !until.IsZero() {
+		args = append(args, "--until", strconv.FormatInt(until.Unix()+1, 10))
+	}
+	return append(args, container)
+}
+
+// Name of source for debug output
+func (s *dockerSource) Name() string {
+	return s.name
+}
+
+func (s *dockerSource) options() []ttail.TimeFileOptions {
+	if !s.json {
+		return []ttail.TimeFileOptions{
+			ttail.WithTimeReAsStr(kubeTimeRe),
+			ttail.WithTimeLayout(time.RFC3339Nano),
+		}
+	}
+	return []ttail.TimeFileOptions{
+		ttail.WithTimeReAsStr(dockerTimeRe),
+		ttail.WithTimeLayout(time.RFC3339Nano),
+		// time of lines unwrapped by dockerFilter, e.g. for -tz
+		ttail.WithExtraTimeReAsStr(kubeTimeRe, time.RFC3339Nano),
+	}
+}
+
+func (s *dockerSource) filter() filter {
+	if !s.json {
+		return nil
+	}
+	return dockerFilter
+}
+
+// dockerFilter decode json-file log line into "time message" like docker logs -t
+func dockerFilter(r *record) bool {
+	var line struct {
+		Log  string `json:"log"`
+		Time string `json:"time"`
+	}
+	if err := json.Unmarshal([]byte(r.Line), &line); err != nil {
+		return true
+	}
+	r.Line = line.Time + " " + strings.TrimSuffix(line.Log, "\n")
+	return true
+}
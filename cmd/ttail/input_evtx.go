@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/sakateka/ttail"
+)
+
+const (
+	// evtxTextTimeRe match time of event in "wevtutil qe /f:text" export,
+	// Z suffix is printed by recent windows versions, time window starts
+	// at Date line of the first event because time is not on the Event line
+	evtxTextTimeRe = `^\s*Date: (\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(?:\.\d+)?)`
+	// evtxXMLTimeRe match time of event in "wevtutil qe /f:xml" export
+	evtxXMLTimeRe = `<TimeCreated SystemTime=['"](\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(?:\.\d+)?Z)['"]`
+
+	evtxSniffSize = 4 << 10
+	evtxMaxEvent  = 4 << 20
+)
+
+var (
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+
+	evtxTextDateRe = regexp.MustCompile(`(?m)^\s*Date: \S+?(Z?)\r?$`)
+)
+
+// evtxSource is a windows event log exported by wevtutil
+type evtxSource struct {
+	source
+	opts []ttail.TimeFileOptions
+}
+
+func (s *evtxSource) options() []ttail.TimeFileOptions {
+	return s.opts
+}
+
+// sniffEventLog detect windows event log exports, utf-16 exports
+// (e.g. redirected in powershell) are converted into utf-8 temporary file,
+// xml exports are rewritten into temporary file with one event per line,
+// binary files are closed with errBinaryFile
+func sniffEventLog(src source, size int64) (source, int64, error) {
+	head := make([]byte, evtxSniffSize)
+	n, err := src.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		src.Close()
+		return nil, 0, err
+	}
+	head = head[:n]
+
+	if bytes.HasPrefix(head, utf16LEBOM) {
+		if !evtxExport(decodeUTF16(head[2:])) {
+			return src, size, nil
+		}
+		src, size, err = transcodeUTF16(src, size)
+		if err != nil {
+			return nil, 0, err
+		}
+		return sniffEventLog(src, size)
+	}
+
+	head = bytes.TrimPrefix(head, utf8BOM)
+	switch {
+	case bytes.HasPrefix(head, []byte("Event[")):
+		opts := []ttail.TimeFileOptions{
+			ttail.WithTimeReAsStr(evtxTextTimeRe),
+			ttail.WithTimeLayout("2006-01-02T15:04:05"),
+		}
+		if m := evtxTextDateRe.FindSubmatch(head); m != nil && len(m[1]) > 0 {
+			opts = append(opts, ttail.WithLocation(time.UTC))
+		}
+		return &evtxSource{source: src, opts: opts}, size, nil
+	case bytes.HasPrefix(head, []byte("<")) && bytes.Contains(head, []byte("<Event")):
+		return splitEvents(src, size)
+	case binaryHead(head):
+		src.Close()
+		return nil, 0, errBinaryFile
+	}
+	return src, size, nil
+}
+
+// evtxExport report whether head of file looks like wevtutil export
+func evtxExport(head []byte) bool {
+	head = bytes.TrimPrefix(head, utf8BOM)
+	return bytes.HasPrefix(head, []byte("Event[")) || bytes.HasPrefix(head, []byte("<"))
+}
+
+// decodeUTF16 decode utf-16le bytes into utf-8
+func decodeUTF16(p []byte) []byte {
+	units := make([]uint16, len(p)/2)
+	for i := range units {
+		units[i] = uint16(p[2*i]) | uint16(p[2*i+1])<<8
+	}
+	var out []byte
+	var buf [utf8.UTFMax]byte
+	for _, r := range utf16.Decode(units) {
+		n := utf8.EncodeRune(buf[:], r)
+		out = append(out, buf[:n]...)
+	}
+	return out
+}
+
+// transcodeUTF16 convert utf-16le src without BOM into utf-8 temporary file
+func transcodeUTF16(src source, size int64) (source, int64, error) {
+	defer src.Close()
+	tmp, err := os.CreateTemp("", "ttail-*.log")
+	if err != nil {
+		return nil, 0, err
+	}
+	file := tempFile{tmp}
+	r := bufio.NewReader(io.NewSectionReader(src, 2, size-2))
+	w := bufio.NewWriter(file)
+
+	var unit [2]byte
+	var pending uint16
+	for {
+		if _, err = io.ReadFull(r, unit[:]); err != nil {
+			break
+		}
+		u := uint16(unit[0]) | uint16(unit[1])<<8
+		switch {
+		case utf16.IsSurrogate(rune(u)) && pending == 0:
+			pending = u
+			continue
+		case pending != 0:
+			_, err = w.WriteRune(utf16.DecodeRune(rune(pending), rune(u)))
+			pending = 0
+		default:
+			_, err = w.WriteRune(rune(u))
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = w.Flush()
+	}
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+var eventEnd = []byte("</Event>")
+
+// splitEvents rewrite xml export into temporary file with one event per line,
+// because wevtutil writes events without line breaks between them
+func splitEvents(src source, size int64) (source, int64, error) {
+	defer src.Close()
+	tmp, err := os.CreateTemp("", "ttail-*.log")
+	if err != nil {
+		return nil, 0, err
+	}
+	file := tempFile{tmp}
+	scanner := bufio.NewScanner(io.NewSectionReader(src, 0, size))
+	scanner.Buffer(make([]byte, 64<<10), evtxMaxEvent)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if idx := bytes.Index(data, eventEnd); idx >= 0 {
+			return idx + len(eventEnd), data[:idx+len(eventEnd)], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+	w := bufio.NewWriter(file)
+	for scanner.Scan() {
+		event := bytes.TrimSpace(scanner.Bytes())
+		if len(event) == 0 {
+			continue
+		}
+		for _, c := range event {
+			if c == '\r' || c == '\n' {
+				c = ' '
+			}
+			w.WriteByte(c)
+		}
+		w.WriteByte('\n')
+	}
+	err = scanner.Err()
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	opts := []ttail.TimeFileOptions{
+		ttail.WithTimeReAsStr(evtxXMLTimeRe),
+		ttail.WithTimeLayout(time.RFC3339Nano),
+	}
+	return &evtxSource{source: file, opts: opts}, info.Size(), nil
+}
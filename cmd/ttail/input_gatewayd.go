@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// gatewaydEntry is a journal entry in json format of systemd-journal-gatewayd,
+// values are strings or arrays of bytes for binary data
+type gatewaydEntry map[string]interface{}
+
+// field return value of entry field
+func (e gatewaydEntry) field(name string) string {
+	switch v := e[name].(type) {
+	case string:
+		return v
+	case []interface{}:
+		data := make([]byte, 0, len(v))
+		for _, b := range v {
+			if n, ok := b.(float64); ok {
+				data = append(data, byte(n))
+			}
+		}
+		return string(data)
+	}
+	return ""
+}
+
+// time of entry, zero if it is unknown
+func (e gatewaydEntry) time() time.Time {
+	usec, err := strconv.ParseInt(e.field("__REALTIME_TIMESTAMP"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, usec*int64(time.Microsecond))
+}
+
+// line of entry formatted as journalctl -o short-iso-precise
+func (e gatewaydEntry) line() []byte {
+	return journalLine(e.time(), e.field)
+}
+
+// errGatewaydRange returned for gatewayd without realtime ranges
+var errGatewaydRange = errors.New("journal gateway does not support time range")
+
+// gatewaydEntries request entries of units in range, range header
+// is "entries=cursor[[:num_skip]:num_entries]" or "realtime=since:until"
+func gatewaydEntries(rng string, follow bool) (*http.Response, error) {
+	addr := strings.TrimSuffix(flagJournalURL, "/")
+	if !strings.HasSuffix(addr, "/entries") {
+		addr += "/entries"
+	}
+	query := url.Values{}
+	if flagUnit != "" {
+		for _, unit := range strings.Split(flagUnit, ",") {
+			if !strings.Contains(unit, ".") {
+				unit += ".service"
+			}
+			query.Add("_SYSTEMD_UNIT", unit)
+		}
+	}
+	if follow {
+		query.Set("follow", "")
+	}
+	if len(query) > 0 {
+		addr += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, addr, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if rng != "" {
+		req.Header.Set("Range", rng)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent {
+		return resp, nil
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest && strings.HasPrefix(rng, "realtime=") {
+		return nil, errGatewaydRange
+	}
+	return nil, fmt.Errorf("%s: %s: %s", addr, resp.Status, bytes.TrimSpace(msg))
+}
+
+// copyGatewayd copy time window of journal of systemd-journal-gatewayd,
+// old gatewayd without realtime ranges sends whole journal and
+// entries before window are skipped here
+func copyGatewayd(duration time.Duration, follow bool) error {
+	tfile := journalTimeFile()
+	from := now()
+	if flagTimeFromLastLine {
+		resp, err := gatewaydEntries("entries=:-1:1", false)
+		if err != nil {
+			return err
+		}
+		var last gatewaydEntry
+		err = json.NewDecoder(resp.Body).Decode(&last)
+		resp.Body.Close()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		from = last.time()
+		if from.IsZero() {
+			log.Debug("[journal]: time not found, copy whole journal")
+		}
+	}
+
+	var since time.Time
+	var rng string
+	if !from.IsZero() {
+		since = from.Add(-duration)
+		rng = "realtime=" + strconv.FormatInt(since.Unix(), 10) + ":"
+	}
+	resp, err := gatewaydEntries(rng, follow)
+	if err == errGatewaydRange {
+		log.Debug("[journal]: read whole journal", zap.Error(err))
+		resp, err = gatewaydEntries("", follow)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	w, err := newWindowWriter("journal:"+flagJournalURL, tfile, nil)
+	if err != nil {
+		return err
+	}
+	log.Debug("[journal]: read", zap.String("url", flagJournalURL), zap.String("unit", flagUnit), zap.Bool("follow", follow))
+
+	br := bufio.NewReaderSize(resp.Body, 1<<16)
+	for {
+		data, rerr := br.ReadBytes('\n')
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			var e gatewaydEntry
+			if err := json.Unmarshal(data, &e); err != nil {
+				return err
+			}
+			if tm := e.time(); !tm.Before(since) {
+				if _, err := w.Write(e.line()); err == errWindowEnd {
+					return closeWindow(w)
+				} else if err != nil {
+					return err
+				}
+			}
+		}
+		// flush when all received entries are written
+		if br.Buffered() == 0 {
+			if err := flushWindow(w); err != nil {
+				return err
+			}
+		}
+		if rerr == io.EOF {
+			break
+		} else if rerr != nil {
+			return rerr
+		}
+	}
+	return closeWindow(w)
+}
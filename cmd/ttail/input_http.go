@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// httpSource read remote file with http Range requests
+type httpSource struct {
+	*readAhead
+	name    string
+	url     string
+	client  *http.Client
+	prepare func(req *http.Request) error
+	size    int64
+}
+
+// openHTTP open url, prepare is called for every request (e.g. to sign it)
+func openHTTP(name, url string, prepare func(req *http.Request) error) (*httpSource, error) {
+	s := &httpSource{
+		name:    name,
+		url:     url,
+		client:  http.DefaultClient,
+		prepare: prepare,
+	}
+	s.readAhead = newReadAhead(0, s.fetch)
+	// request first byte to find out size and check range support
+	resp, err := s.get(0, 0)
+	if err == io.EOF {
+		// empty file
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	contentRange := resp.Header.Get("Content-Range")
+	idx := strings.LastIndexByte(contentRange, '/')
+	if idx < 0 {
+		return nil, fmt.Errorf("%s: bad Content-Range %q", name, contentRange)
+	}
+	s.size, err = strconv.ParseInt(contentRange[idx+1:], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unknown size in Content-Range %q", name, contentRange)
+	}
+	s.readAhead.size = s.size
+	return s, nil
+}
+
+// Name of source for debug output
+func (s *httpSource) Name() string {
+	return s.name
+}
+
+// Close idle connections
+func (s *httpSource) Close() error {
+	if t, ok := s.client.Transport.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+	return nil
+}
+
+// get request bytes from start to end inclusive
+func (s *httpSource) get(start, end int64) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	// transparent compression breaks byte offsets
+	req.Header.Set("Accept-Encoding", "identity")
+	if s.prepare != nil {
+		if err := s.prepare(req); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return resp, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		return nil, io.EOF
+	case http.StatusOK:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: server does not support range requests", s.name)
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("%s: unexpected status %s", s.name, resp.Status)
+}
+
+func (s *httpSource) fetch(p []byte, off int64) (int, error) {
+	resp, err := s.get(off, off+int64(len(p))-1)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return io.ReadFull(resp.Body, p)
+}
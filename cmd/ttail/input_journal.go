@@ -0,0 +1,727 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"go.uber.org/zap"
+)
+
+// systemd journal file format, see https://systemd.io/JOURNAL_FILE_FORMAT/
+const (
+	journalSignature  = "LPKSHHRH"
+	journalHeaderSize = 208
+
+	// incompatible flags of header
+	journalCompressedXZ   = 1
+	journalCompressedLZ4  = 2
+	journalKeyedHash      = 4
+	journalCompressedZSTD = 8
+	journalCompact        = 16
+
+	// flags of data object
+	journalObjectXZ   = 1
+	journalObjectLZ4  = 2
+	journalObjectZSTD = 4
+
+	journalObjectEntry      = 3
+	journalObjectEntryArray = 6
+
+	journalObjectHeader = 16
+	journalMaxObject    = 64 << 20 // 64mb
+	// journalNamePrefix is read from data objects to check field name
+	// before payload is read
+	journalNamePrefix = 64
+	journalCacheSize  = 1 << 16
+)
+
+// journalFields are fields of entries used to format and match them
+var journalFields = map[string]bool{
+	"MESSAGE":           true,
+	"_HOSTNAME":         true,
+	"SYSLOG_IDENTIFIER": true,
+	"_COMM":             true,
+	"_PID":              true,
+	"_SYSTEMD_UNIT":     true,
+	"UNIT":              true,
+}
+
+var (
+	errJournalFormat = errors.New("not a journal file or unsupported journal format")
+	errJournalLZ4    = errors.New("bad lz4 data of journal")
+
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(journalMaxObject))
+)
+
+// journalEntry is an entry of journal file with fields used by ttail
+type journalEntry struct {
+	realtime time.Time
+	seqnum   uint64
+	xorHash  uint64
+	fields   map[string]string
+}
+
+// line of entry formatted as journalctl -o short-iso-precise
+func (e *journalEntry) line() []byte {
+	return journalLine(e.realtime, func(name string) string { return e.fields[name] })
+}
+
+// match report whether entry belongs to units, messages of systemd
+// about unit are matched by UNIT field like journalctl does
+func (e *journalEntry) match(units []string) bool {
+	if len(units) == 0 {
+		return true
+	}
+	for _, unit := range units {
+		if e.fields["_SYSTEMD_UNIT"] == unit || e.fields["_PID"] == "1" && e.fields["UNIT"] == unit {
+			return true
+		}
+	}
+	return false
+}
+
+// journalArray is an entry array object of global chain of entries
+type journalArray struct {
+	offset uint64
+	// realtime of the first entry of array
+	first time.Time
+}
+
+// journalFile read entries of journal file in order of entry arrays,
+// it is in order of time except clock jumps
+type journalFile struct {
+	file      *os.File
+	compact   bool
+	size      int64
+	nEntries  uint64
+	arrayHead uint64
+	head      time.Time
+	tail      time.Time
+
+	arrays []journalArray
+	// position of the next entry
+	array int
+	items []uint64
+	item  int
+
+	// fields of data objects by offset, nil for fields not used
+	cache map[uint64]*journalField
+}
+
+type journalField struct {
+	name  string
+	value string
+}
+
+// openJournalFile open journal file and read its header
+func openJournalFile(name string) (*journalFile, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	j := &journalFile{file: file, cache: make(map[uint64]*journalField)}
+	if err := j.readHeader(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return j, nil
+}
+
+func (j *journalFile) Close() error {
+	return j.file.Close()
+}
+
+func (j *journalFile) readHeader() error {
+	h := make([]byte, journalHeaderSize)
+	if _, err := j.file.ReadAt(h, 0); err != nil {
+		if err == io.EOF {
+			return errJournalFormat
+		}
+		return err
+	}
+	if string(h[:8]) != journalSignature {
+		return errJournalFormat
+	}
+	incompatible := binary.LittleEndian.Uint32(h[12:])
+	known := uint32(journalCompressedXZ | journalCompressedLZ4 | journalKeyedHash | journalCompressedZSTD | journalCompact)
+	if incompatible&^known != 0 {
+		return fmt.Errorf("unsupported incompatible flags of journal: %#x", incompatible&^known)
+	}
+	j.compact = incompatible&journalCompact != 0
+	headerSize := binary.LittleEndian.Uint64(h[88:])
+	arenaSize := binary.LittleEndian.Uint64(h[96:])
+	j.size = int64(headerSize + arenaSize)
+	j.nEntries = binary.LittleEndian.Uint64(h[152:])
+	j.arrayHead = binary.LittleEndian.Uint64(h[176:])
+	j.head = journalTime(binary.LittleEndian.Uint64(h[184:]))
+	j.tail = journalTime(binary.LittleEndian.Uint64(h[192:]))
+	return nil
+}
+
+// journalTime convert microseconds of realtime into time
+func journalTime(usec uint64) time.Time {
+	return time.Unix(0, int64(usec)*int64(time.Microsecond))
+}
+
+// readObject read object at offset with at most limit bytes of it,
+// type and size of whole object are returned with data
+func (j *journalFile) readObject(offset uint64, limit int) (byte, []byte, uint64, error) {
+	var h [journalObjectHeader]byte
+	if _, err := j.file.ReadAt(h[:], int64(offset)); err != nil {
+		return 0, nil, 0, fmt.Errorf("read journal object at %d: %v", offset, err)
+	}
+	size := binary.LittleEndian.Uint64(h[8:])
+	if size < journalObjectHeader || size > journalMaxObject || int64(offset+size) > j.size {
+		return 0, nil, 0, fmt.Errorf("bad journal object at %d", offset)
+	}
+	if limit <= 0 || uint64(limit) > size {
+		limit = int(size)
+	}
+	data := make([]byte, limit)
+	copy(data, h[:])
+	if _, err := j.file.ReadAt(data[journalObjectHeader:], int64(offset)+journalObjectHeader); err != nil && err != io.EOF {
+		return 0, nil, 0, fmt.Errorf("read journal object at %d: %v", offset, err)
+	}
+	return h[0], data, size, nil
+}
+
+// readArrays read global chain of entry arrays with the first
+// entry time of every array, so entries are searched by time
+func (j *journalFile) readArrays() error {
+	j.arrays = j.arrays[:0]
+	itemSize := 8
+	if j.compact {
+		itemSize = 4
+	}
+	for offset := j.arrayHead; offset != 0; {
+		kind, data, _, err := j.readObject(offset, journalObjectHeader+8+itemSize)
+		if err != nil {
+			return err
+		}
+		if kind != journalObjectEntryArray || len(data) < journalObjectHeader+8+itemSize {
+			return fmt.Errorf("bad entry array at %d", offset)
+		}
+		first := j.item64(data[journalObjectHeader+8:])
+		if first == 0 {
+			break
+		}
+		tm, err := j.entryTime(first)
+		if err != nil {
+			return err
+		}
+		j.arrays = append(j.arrays, journalArray{offset: offset, first: tm})
+		offset = binary.LittleEndian.Uint64(data[journalObjectHeader:])
+		if len(j.arrays) > 64 && uint64(len(j.arrays)) > j.nEntries {
+			return errors.New("loop of journal entry arrays")
+		}
+	}
+	return nil
+}
+
+// item64 return offset of the first item at b of entry array or entry,
+// offsets of compact journal are 32 bit
+func (j *journalFile) item64(b []byte) uint64 {
+	if j.compact {
+		return uint64(binary.LittleEndian.Uint32(b))
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// loadArray read offsets of entries of array i
+func (j *journalFile) loadArray(i int) error {
+	a := j.arrays[i]
+	_, data, _, err := j.readObject(a.offset, 0)
+	if err != nil {
+		return err
+	}
+	itemSize := 8
+	if j.compact {
+		itemSize = 4
+	}
+	j.items = j.items[:0]
+	for b := data[journalObjectHeader+8:]; len(b) >= itemSize; b = b[itemSize:] {
+		item := j.item64(b)
+		if item == 0 {
+			// the last array is filled partially
+			break
+		}
+		j.items = append(j.items, item)
+	}
+	j.array, j.item = i, 0
+	return nil
+}
+
+// entryTime read realtime of entry at offset
+func (j *journalFile) entryTime(offset uint64) (time.Time, error) {
+	var b [8]byte
+	if _, err := j.file.ReadAt(b[:], int64(offset)+journalObjectHeader+8); err != nil {
+		return time.Time{}, fmt.Errorf("read journal entry at %d: %v", offset, err)
+	}
+	return journalTime(binary.LittleEndian.Uint64(b[:])), nil
+}
+
+// seek position of the first entry at or after since
+func (j *journalFile) seek(since time.Time) error {
+	if err := j.readArrays(); err != nil {
+		return err
+	}
+	if len(j.arrays) == 0 {
+		j.array, j.items, j.item = 0, j.items[:0], 0
+		return nil
+	}
+	// the last array starting before since
+	i := sort.Search(len(j.arrays), func(i int) bool {
+		return !j.arrays[i].first.Before(since)
+	}) - 1
+	if i < 0 {
+		i = 0
+	}
+	if err := j.loadArray(i); err != nil {
+		return err
+	}
+	var err error
+	j.item = sort.Search(len(j.items), func(k int) bool {
+		if err != nil {
+			return true
+		}
+		var tm time.Time
+		tm, err = j.entryTime(j.items[k])
+		return !tm.Before(since)
+	})
+	return err
+}
+
+// next read entry at position and move to the next one,
+// io.EOF is returned after the last entry
+func (j *journalFile) next() (*journalEntry, error) {
+	for j.item >= len(j.items) {
+		if j.array+1 >= len(j.arrays) {
+			return nil, io.EOF
+		}
+		if err := j.loadArray(j.array + 1); err != nil {
+			return nil, err
+		}
+	}
+	e, err := j.readEntry(j.items[j.item])
+	if err != nil {
+		return nil, err
+	}
+	j.item++
+	return e, nil
+}
+
+// last return the last entry matching units, entries are read backwards
+func (j *journalFile) last(units []string) (*journalEntry, error) {
+	if err := j.readArrays(); err != nil {
+		return nil, err
+	}
+	for i := len(j.arrays) - 1; i >= 0; i-- {
+		if err := j.loadArray(i); err != nil {
+			return nil, err
+		}
+		for k := len(j.items) - 1; k >= 0; k-- {
+			e, err := j.readEntry(j.items[k])
+			if err != nil {
+				return nil, err
+			}
+			if e.match(units) {
+				return e, nil
+			}
+		}
+	}
+	return nil, io.EOF
+}
+
+// readEntry read entry object and its fields used by ttail
+func (j *journalFile) readEntry(offset uint64) (*journalEntry, error) {
+	kind, data, _, err := j.readObject(offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	if kind != journalObjectEntry || len(data) < 64 {
+		return nil, fmt.Errorf("bad journal entry at %d", offset)
+	}
+	e := &journalEntry{
+		seqnum:   binary.LittleEndian.Uint64(data[16:]),
+		realtime: journalTime(binary.LittleEndian.Uint64(data[24:])),
+		xorHash:  binary.LittleEndian.Uint64(data[56:]),
+		fields:   make(map[string]string),
+	}
+	itemSize := 16
+	if j.compact {
+		itemSize = 4
+	}
+	for b := data[64:]; len(b) >= itemSize; b = b[itemSize:] {
+		f, err := j.readField(j.item64(b))
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			e.fields[f.name] = f.value
+		}
+	}
+	return e, nil
+}
+
+// readField read field of data object, nil is returned for fields
+// not used by ttail, they are cached except messages
+func (j *journalFile) readField(offset uint64) (*journalField, error) {
+	if f, ok := j.cache[offset]; ok {
+		return f, nil
+	}
+	payloadStart := journalObjectHeader + 48
+	if j.compact {
+		payloadStart += 8
+	}
+	_, data, size, err := j.readObject(offset, payloadStart+journalNamePrefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < payloadStart {
+		return nil, fmt.Errorf("bad journal data at %d", offset)
+	}
+	flags := data[1]
+	payload := data[payloadStart:]
+	if flags == 0 {
+		// name of uncompressed field is checked before whole payload is read
+		if idx := bytes.IndexByte(payload, '='); idx >= 0 && !journalFields[string(payload[:idx])] {
+			j.cacheField(offset, nil)
+			return nil, nil
+		}
+	}
+	if uint64(len(data)) < size {
+		if _, data, _, err = j.readObject(offset, 0); err != nil {
+			return nil, err
+		}
+		payload = data[payloadStart:]
+	}
+	if payload, err = journalPayload(flags, payload); err != nil {
+		return nil, fmt.Errorf("journal data at %d: %v", offset, err)
+	}
+	idx := bytes.IndexByte(payload, '=')
+	if idx < 0 || !journalFields[string(payload[:idx])] {
+		j.cacheField(offset, nil)
+		return nil, nil
+	}
+	f := &journalField{name: string(payload[:idx]), value: string(payload[idx+1:])}
+	if f.name != "MESSAGE" {
+		j.cacheField(offset, f)
+	}
+	return f, nil
+}
+
+func (j *journalFile) cacheField(offset uint64, f *journalField) {
+	if len(j.cache) >= journalCacheSize {
+		j.cache = make(map[uint64]*journalField)
+	}
+	j.cache[offset] = f
+}
+
+// journalPayload decompress payload of data object by its flags
+func journalPayload(flags byte, payload []byte) ([]byte, error) {
+	switch {
+	case flags&journalObjectZSTD != 0:
+		return zstdDecoder.DecodeAll(payload, nil)
+	case flags&journalObjectXZ != 0:
+		zr, err := xz.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(io.LimitReader(zr, journalMaxObject))
+	case flags&journalObjectLZ4 != 0:
+		if len(payload) < 8 {
+			return nil, errJournalLZ4
+		}
+		// lz4 block follows little endian uncompressed size
+		size := binary.LittleEndian.Uint64(payload)
+		if size > journalMaxObject {
+			return nil, errJournalLZ4
+		}
+		return lz4Block(payload[8:], int(size))
+	}
+	return payload, nil
+}
+
+// lz4Block decompress lz4 block of size bytes
+func lz4Block(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, size)
+	pos := 0
+	// length read extra bytes of length n of literals or match
+	length := func(n int) (int, error) {
+		if n < 15 {
+			return n, nil
+		}
+		for pos < len(src) {
+			b := src[pos]
+			pos++
+			n += int(b)
+			if b != 255 {
+				return n, nil
+			}
+		}
+		return 0, errJournalLZ4
+	}
+	for pos < len(src) {
+		token := src[pos]
+		pos++
+		lit, err := length(int(token >> 4))
+		if err != nil || pos+lit > len(src) || len(dst)+lit > size {
+			return nil, errJournalLZ4
+		}
+		dst = append(dst, src[pos:pos+lit]...)
+		pos += lit
+		if pos == len(src) {
+			// the last sequence has only literals
+			break
+		}
+		if pos+2 > len(src) {
+			return nil, errJournalLZ4
+		}
+		offset := int(src[pos]) | int(src[pos+1])<<8
+		pos += 2
+		match, err := length(int(token & 15))
+		if err != nil || offset == 0 || offset > len(dst) || len(dst)+match+4 > size {
+			return nil, errJournalLZ4
+		}
+		start := len(dst) - offset
+		for k := 0; k < match+4; k++ {
+			// match may overlap bytes it writes
+			dst = append(dst, dst[start+k])
+		}
+	}
+	if len(dst) != size {
+		return nil, errJournalLZ4
+	}
+	return dst, nil
+}
+
+// localJournalDirs return directories of journal files of local machine
+func localJournalDirs() []string {
+	dirs := []string{"/var/log/journal", "/run/log/journal"}
+	if id, err := ioutil.ReadFile("/etc/machine-id"); err == nil {
+		for i, dir := range dirs {
+			dirs[i] = filepath.Join(dir, strings.TrimSpace(string(id)))
+		}
+	}
+	return dirs
+}
+
+// journalFiles return journal files of directories and their subdirectories,
+// e.g. /var/log/journal with directory of every machine
+func journalFiles(dirs []string) []string {
+	var files []string
+	for _, dir := range dirs {
+		for _, pattern := range []string{"*.journal", "*/*.journal"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			files = append(files, matches...)
+		}
+	}
+	return files
+}
+
+// journalUnits return units of -unit in the form of _SYSTEMD_UNIT field
+func journalUnits() []string {
+	var units []string
+	if flagUnit == "" {
+		return units
+	}
+	for _, unit := range strings.Split(flagUnit, ",") {
+		if !strings.Contains(unit, ".") {
+			unit += ".service"
+		}
+		units = append(units, unit)
+	}
+	return units
+}
+
+// journalReader merge entries of journal files in order of time
+type journalReader struct {
+	dirs  []string
+	units []string
+}
+
+// last return time of the last entry of units, zero if it is not found
+func (r *journalReader) last() time.Time {
+	var last time.Time
+	for _, name := range journalFiles(r.dirs) {
+		j, err := openJournalFile(name)
+		if err != nil {
+			log.Debug("[journal]: skip file", zap.String("file", name), zap.Error(err))
+			continue
+		}
+		if j.tail.After(last) {
+			if e, err := j.last(r.units); err == nil && e.realtime.After(last) {
+				last = e.realtime
+			} else if err != nil && err != io.EOF {
+				log.Debug("[journal]: read file", zap.String("file", name), zap.Error(err))
+			}
+		}
+		j.Close()
+	}
+	return last
+}
+
+// read pass entries from since to until (zero is the end of journal)
+// of all files to fn in order of time, files are opened on every read,
+// so rotated and new files are read by the next read in follow mode
+func (r *journalReader) read(since, until time.Time, fn func(e *journalEntry) error) error {
+	type head struct {
+		file  *journalFile
+		entry *journalEntry
+	}
+	var heads []head
+	defer func() {
+		for _, h := range heads {
+			h.file.Close()
+		}
+	}()
+	for _, name := range journalFiles(r.dirs) {
+		j, err := openJournalFile(name)
+		if err != nil {
+			log.Debug("[journal]: skip file", zap.String("file", name), zap.Error(err))
+			continue
+		}
+		if j.nEntries == 0 || j.tail.Before(since) || !until.IsZero() && j.head.After(until) {
+			j.Close()
+			continue
+		}
+		heads = append(heads, head{file: j})
+		if err := j.seek(since); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	for i := 0; i < len(heads); {
+		e, err := r.nextMatch(heads[i].file)
+		if err == io.EOF {
+			heads[i].file.Close()
+			heads = append(heads[:i], heads[i+1:]...)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("%s: %v", heads[i].file.file.Name(), err)
+		}
+		heads[i].entry = e
+		i++
+	}
+	for len(heads) > 0 {
+		min := 0
+		for i := range heads {
+			if heads[i].entry.realtime.Before(heads[min].entry.realtime) {
+				min = i
+			}
+		}
+		e := heads[min].entry
+		if !until.IsZero() && e.realtime.After(until) {
+			return nil
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+		next, err := r.nextMatch(heads[min].file)
+		if err == io.EOF {
+			heads[min].file.Close()
+			heads = append(heads[:min], heads[min+1:]...)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("%s: %v", heads[min].file.file.Name(), err)
+		}
+		heads[min].entry = next
+	}
+	return nil
+}
+
+// nextMatch return the next entry of file matching units
+func (r *journalReader) nextMatch(j *journalFile) (*journalEntry, error) {
+	for {
+		e, err := j.next()
+		if err != nil || e.match(r.units) {
+			return e, err
+		}
+	}
+}
+
+// copyJournalFiles copy time window of journal files of dirs,
+// it reads files natively without journalctl
+func copyJournalFiles(dirs []string, duration time.Duration, follow bool) error {
+	r := &journalReader{dirs: dirs, units: journalUnits()}
+	from := now()
+	if flagTimeFromLastLine {
+		if from = r.last(); from.IsZero() {
+			log.Debug("[journal]: time not found, copy whole journal")
+		}
+	}
+	var since time.Time
+	if !from.IsZero() {
+		since = from.Add(-duration)
+	}
+	until, err := windowEnd(from)
+	if err != nil {
+		return err
+	}
+	w, err := newWindowWriter("journal:"+strings.Join(dirs, ","), journalTimeFile(), nil)
+	if err != nil {
+		return err
+	}
+	log.Debug("[journal]: read files", zap.Strings("dirs", dirs), zap.String("unit", flagUnit), zap.Bool("follow", follow))
+
+	// entries of the last time are remembered to skip them on the next read
+	var last time.Time
+	seen := make(map[[2]uint64]bool)
+	write := func(e *journalEntry) error {
+		key := [2]uint64{e.seqnum, e.xorHash}
+		if e.realtime.Before(last) || e.realtime.Equal(last) && seen[key] {
+			return nil
+		}
+		if e.realtime.After(last) {
+			last = e.realtime
+			seen = make(map[[2]uint64]bool)
+		}
+		seen[key] = true
+		_, err := w.Write(e.line())
+		return err
+	}
+	for {
+		err := r.read(since, until, write)
+		if err == nil {
+			err = flushWindow(w)
+		}
+		if err == errWindowEnd {
+			return closeWindow(w)
+		} else if err != nil {
+			return err
+		}
+		if !follow {
+			return closeWindow(w)
+		}
+		if !last.IsZero() {
+			since = last
+		}
+		time.Sleep(flagFollowInterval)
+	}
+}
+
+// windowEnd return end of time window by -until, duration is counted
+// back from time of from like -n, zero time is the end of log
+func windowEnd(from time.Time) (time.Time, error) {
+	if flagUntil == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(flagUntil); err == nil {
+		return from.Add(-d + flagSkew), nil
+	}
+	until, err := time.Parse(time.RFC3339, flagUntil)
+	if err != nil {
+		return until, errors.New("bad -until, want duration or RFC3339 time: " + flagUntil)
+	}
+	return until.Add(flagSkew), nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// splitBucketURL parse "s3://bucket/key" into bucket and key
+func splitBucketURL(name string) (bucket, key string, err error) {
+	rest := name[strings.Index(name, "://")+3:]
+	idx := strings.IndexByte(rest, '/')
+	if idx <= 0 || idx == len(rest)-1 {
+		return "", "", fmt.Errorf("%s: expected <scheme>://bucket/key", name)
+	}
+	return rest[:idx], rest[idx+1:], nil
+}
+
+// escapePath escape path as required by aws signature
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// openS3 open object of aws s3 or compatible storage, credentials
+// are taken from AWS_* environment, without them request is anonymous
+func openS3(name string) (*httpSource, error) {
+	bucket, key, err := splitBucketURL(name)
+	if err != nil {
+		return nil, err
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	var host, path string
+	scheme := "https"
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+		// path style for s3 compatible storages like minio
+		if idx := strings.Index(endpoint, "://"); idx > 0 {
+			scheme, endpoint = endpoint[:idx], endpoint[idx+3:]
+		}
+		host = strings.TrimSuffix(endpoint, "/")
+		path = "/" + bucket + "/" + key
+	} else {
+		host = bucket + ".s3." + region + ".amazonaws.com"
+		path = "/" + key
+	}
+	signer := &awsSigner{
+		region:    region,
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:     os.Getenv("AWS_SESSION_TOKEN"),
+		path:      escapePath(path),
+	}
+	return openHTTP(name, scheme+"://"+host+signer.path, signer.sign)
+}
+
+// openGS open object of google cloud storage, access token
+// is taken from GOOGLE_OAUTH_ACCESS_TOKEN (gcloud auth print-access-token)
+func openGS(name string) (*httpSource, error) {
+	bucket, key, err := splitBucketURL(name)
+	if err != nil {
+		return nil, err
+	}
+	token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	url := "https://storage.googleapis.com/" + bucket + "/" + escapePath(key)
+	return openHTTP(name, url, func(req *http.Request) error {
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return nil
+	})
+}
+
+// awsSigner sign GET requests with aws signature version 4
+type awsSigner struct {
+	region    string
+	accessKey string
+	secretKey string
+	token     string
+	path      string
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func (s *awsSigner) sign(req *http.Request) error {
+	if s.accessKey == "" {
+		return nil
+	}
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := "UNSIGNED-PAYLOAD"
+
+	req.URL.RawPath = s.path
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	headers := "host:" + req.URL.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n"
+	if s.token != "" {
+		req.Header.Set("X-Amz-Security-Token", s.token)
+		signed = append(signed, "x-amz-security-token")
+		headers += "x-amz-security-token:" + s.token + "\n"
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	canonical := strings.Join([]string{
+		http.MethodGet, s.path, "", headers, signedHeaders, payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+	return nil
+}
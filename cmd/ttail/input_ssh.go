@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// sftp protocol version 3 packet types and constants
+const (
+	sshFxpInit    = 1
+	sshFxpVersion = 2
+	sshFxpOpen    = 3
+	sshFxpClose   = 4
+	sshFxpRead    = 5
+	sshFxpFstat   = 8
+	sshFxpStatus  = 101
+	sshFxpHandle  = 102
+	sshFxpData    = 103
+	sshFxpAttrs   = 105
+
+	sshFxfRead          = 1
+	sshFileXferAttrSize = 1
+	sshFxEOF            = 1
+
+	sftpMaxRead = 32 << 10 // 32kb, guaranteed by all servers
+)
+
+// errSFTPLength returned for discarded packet of bad length,
+// the next packet is read from its start
+var errSFTPLength = errors.New("bad sftp packet length")
+
+// splitSSHName parse "[user@]host:/path" argument
+func splitSSHName(name string) (dest, path string, ok bool) {
+	idx := strings.IndexByte(name, ':')
+	if idx <= 0 || idx == len(name)-1 {
+		return "", "", false
+	}
+	dest, path = name[:idx], name[idx+1:]
+	if strings.ContainsRune(dest, '/') {
+		return "", "", false
+	}
+	if !strings.HasPrefix(path, "/") && !strings.ContainsRune(dest, '@') {
+		return "", "", false
+	}
+	if _, err := os.Stat(name); err == nil {
+		// local file with ':' in name
+		return "", "", false
+	}
+	return dest, path, true
+}
+
+// sftpSource read remote file over sftp subsystem of ssh,
+// so nothing except sshd is needed on the remote host
+type sftpSource struct {
+	*readAhead
+	name   string
+	cmd    *exec.Cmd
+	w      io.WriteCloser
+	r      *bufio.Reader
+	handle string
+	size   int64
+	id     uint32
+	// broken is an error which left unread bytes of responses,
+	// so the session is out of sync and is not used anymore
+	broken error
+}
+
+func openSSH(dest, path string) (*sftpSource, error) {
+	s := &sftpSource{
+		name: dest + ":" + path,
+		cmd:  exec.Command("ssh", "-s", dest, "sftp"),
+	}
+	s.cmd.Stderr = os.Stderr
+	var err error
+	if s.w, err = s.cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	stdout, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	s.r = bufio.NewReader(stdout)
+	if err := s.cmd.Start(); err != nil {
+		return nil, err
+	}
+	if err := s.init(path); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("%s: %s", s.name, err)
+	}
+	s.readAhead = newReadAhead(s.size, s.fetch)
+	return s, nil
+}
+
+func (s *sftpSource) init(path string) error {
+	if err := s.send(sftpPacket{sshFxpInit}.uint32(3)); err != nil {
+		return err
+	}
+	typ, _, err := s.recv()
+	if err != nil {
+		return err
+	} else if typ != sshFxpVersion {
+		return fmt.Errorf("unexpected sftp packet %d instead of version", typ)
+	}
+
+	id := s.nextID()
+	if err := s.send(sftpPacket{sshFxpOpen}.uint32(id).string(path).uint32(sshFxfRead).uint32(0)); err != nil {
+		return err
+	}
+	data, err := s.expect(id, sshFxpHandle)
+	if err != nil {
+		return err
+	}
+	if s.handle, _, err = parseString(data); err != nil {
+		return err
+	}
+
+	id = s.nextID()
+	if err := s.send(sftpPacket{sshFxpFstat}.uint32(id).string(s.handle)); err != nil {
+		return err
+	}
+	data, err = s.expect(id, sshFxpAttrs)
+	if err != nil {
+		return err
+	}
+	if len(data) < 12 || parseUint32(data)&sshFileXferAttrSize == 0 {
+		return errors.New("sftp server does not report file size")
+	}
+	s.size = int64(parseUint32(data[4:]))<<32 | int64(parseUint32(data[8:]))
+	return nil
+}
+
+// Name of source for debug output
+func (s *sftpSource) Name() string {
+	return s.name
+}
+
+// Close remote file and ssh connection
+func (s *sftpSource) Close() error {
+	if s.handle != "" {
+		_ = s.send(sftpPacket{sshFxpClose}.uint32(s.nextID()).string(s.handle))
+		s.handle = ""
+	}
+	s.w.Close()
+	return s.cmd.Wait()
+}
+
+func (s *sftpSource) nextID() uint32 {
+	s.id++
+	return s.id
+}
+
+// fetch send read requests for all chunks of p at once
+// and then collect responses, so only one round trip is needed
+func (s *sftpSource) fetch(p []byte, off int64) (int, error) {
+	if s.broken != nil {
+		return 0, s.broken
+	}
+	first := s.id + 1
+	for pos := 0; pos < len(p); pos += sftpMaxRead {
+		size := len(p) - pos
+		if size > sftpMaxRead {
+			size = sftpMaxRead
+		}
+		pkt := sftpPacket{sshFxpRead}.uint32(s.nextID()).string(s.handle).uint64(uint64(off) + uint64(pos)).uint32(uint32(size))
+		if err := s.send(pkt); err != nil {
+			return 0, err
+		}
+	}
+
+	got := make([]int, int(s.id-first)+1)
+	var err, bad error
+	for range got {
+		typ, data, rerr := s.recv()
+		if rerr == errSFTPLength {
+			if bad == nil {
+				bad = rerr
+			}
+			continue
+		} else if rerr != nil {
+			s.broken = rerr
+			return 0, rerr
+		}
+		// responses of all requests are read even after bad one,
+		// so they are not left on the channel for the next fetch
+		if bad != nil {
+			continue
+		}
+		idx := int(parseUint32(data) - first)
+		if idx < 0 || idx >= len(got) {
+			bad = errors.New("unexpected sftp response id")
+			continue
+		}
+		switch typ {
+		case sshFxpData:
+			chunk, _, perr := parseString(data[4:])
+			if perr != nil {
+				bad = perr
+				continue
+			}
+			got[idx] = copy(p[idx*sftpMaxRead:], chunk)
+		case sshFxpStatus:
+			if len(data) < 8 {
+				bad = errors.New("short sftp status")
+				continue
+			}
+			if code := parseUint32(data[4:]); code != sshFxEOF && err == nil {
+				err = fmt.Errorf("sftp read error, code %d", code)
+			}
+		default:
+			bad = fmt.Errorf("unexpected sftp packet %d instead of data", typ)
+		}
+	}
+	if bad != nil {
+		return 0, bad
+	}
+
+	// only data contiguous from the beginning of p is returned
+	n := 0
+	for _, size := range got {
+		n += size
+		if size < sftpMaxRead {
+			break
+		}
+	}
+	if n > len(p) {
+		n = len(p)
+	}
+	if n < len(p) && err == nil {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// expect read response for request id of type typ
+func (s *sftpSource) expect(id uint32, typ byte) ([]byte, error) {
+	rtyp, data, err := s.recv()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 || parseUint32(data) != id {
+		return nil, errors.New("unexpected sftp response id")
+	}
+	if rtyp == sshFxpStatus {
+		msg := ""
+		if len(data) >= 8 {
+			msg, _, _ = parseString(data[8:])
+		}
+		return nil, fmt.Errorf("sftp error: %s", msg)
+	} else if rtyp != typ {
+		return nil, fmt.Errorf("unexpected sftp packet %d instead of %d", rtyp, typ)
+	}
+	return data[4:], nil
+}
+
+func (s *sftpSource) send(p sftpPacket) error {
+	length := sftpPacket(nil).uint32(uint32(len(p)))
+	if _, err := s.w.Write(append(length, p...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *sftpSource) recv() (byte, []byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(s.r, length[:]); err != nil {
+		return 0, nil, err
+	}
+	size := parseUint32(length[:])
+	if size < 5 || size > 1<<20 {
+		if _, err := io.CopyN(ioutil.Discard, s.r, int64(size)); err != nil {
+			return 0, nil, err
+		}
+		return 0, nil, errSFTPLength
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(s.r, data); err != nil {
+		return 0, nil, err
+	}
+	return data[0], data[1:], nil
+}
+
+// sftpPacket build sftp request in wire format
+type sftpPacket []byte
+
+func (p sftpPacket) uint32(v uint32) sftpPacket {
+	return append(p, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (p sftpPacket) uint64(v uint64) sftpPacket {
+	return p.uint32(uint32(v >> 32)).uint32(uint32(v))
+}
+
+func (p sftpPacket) string(s string) sftpPacket {
+	return append(p.uint32(uint32(len(s))), s...)
+}
+
+func parseUint32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+func parseString(b []byte) (string, []byte, error) {
+	if len(b) < 4 {
+		return "", nil, errors.New("short sftp string")
+	}
+	size := parseUint32(b)
+	if uint32(len(b)-4) < size {
+		return "", nil, errors.New("short sftp string")
+	}
+	return string(b[4 : 4+size]), b[4+size:], nil
+}
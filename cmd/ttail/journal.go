@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sakateka/ttail"
+	"go.uber.org/zap"
+)
+
+var (
+	flagUnit       string
+	flagJournalURL string
+	flagJournalDir string
+)
+
+// time of journalctl -o short-iso-precise output
+const (
+	journalTimeRe     = `^(\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d+[+-]\d{4}) `
+	journalTimeLayout = "2006-01-02T15:04:05.999999-0700"
+)
+
+func journalFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagUnit, "unit", "", "read journal of systemd units (comma separated) by journalctl from PATH or from local journal files without it instead of files")
+	fs.StringVar(&flagJournalURL, "journal-url", "", "read journal from systemd-journal-gatewayd, e.g. http://host:19531, instead of journalctl")
+	fs.StringVar(&flagJournalDir, "journal-dir", "", "read journal files of directory, e.g. /var/log/journal mounted from host, without journalctl")
+}
+
+// journalSelected report whether journal is read instead of files
+func journalSelected() bool {
+	return flagUnit != "" || flagJournalURL != "" || flagJournalDir != ""
+}
+
+// journalTimeFile return tfile used only to parse time of journal lines
+func journalTimeFile() *ttail.TFile {
+	return ttail.NewTimeReader(nil, 0,
+		ttail.WithTimeReAsStr(journalTimeRe),
+		ttail.WithTimeLayout(journalTimeLayout),
+	)
+}
+
+// journalLine format entry with time tm and fields as journalctl -o short-iso-precise
+func journalLine(tm time.Time, field func(name string) string) []byte {
+	var b bytes.Buffer
+	b.WriteString(tm.Format(journalTimeLayout))
+	b.WriteByte(' ')
+	b.WriteString(field("_HOSTNAME"))
+	b.WriteByte(' ')
+	ident := field("SYSLOG_IDENTIFIER")
+	if ident == "" {
+		ident = field("_COMM")
+	}
+	b.WriteString(ident)
+	if pid := field("_PID"); pid != "" {
+		b.WriteString("[" + pid + "]")
+	}
+	b.WriteString(": ")
+	b.WriteString(strings.TrimRight(field("MESSAGE"), "\n"))
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+func journalctl(args ...string) *exec.Cmd {
+	var units []string
+	for _, unit := range strings.Split(flagUnit, ",") {
+		units = append(units, "--unit", unit)
+	}
+	args = append(units, args...)
+	args = append(args, "--output", "short-iso-precise", "--no-pager", "--quiet")
+	cmd := exec.Command("journalctl", args...)
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// copyJournal copy time window of systemd units journal to stdout,
+// journalctl is preferred so it works with any journal file format,
+// journal files are read natively if it is not found
+func copyJournal(duration time.Duration, follow bool) error {
+	if flagJournalURL != "" {
+		return copyGatewayd(duration, follow)
+	}
+	if flagJournalDir != "" {
+		return copyJournalFiles([]string{flagJournalDir}, duration, follow)
+	}
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		log.Debug("[journal]: journalctl is not found, read journal files", zap.Error(err))
+		return copyJournalFiles(localJournalDirs(), duration, follow)
+	}
+	tfile := journalTimeFile()
+	from := now()
+	if flagTimeFromLastLine {
+		out, err := journalctl("--lines", "1").Output()
+		if err != nil {
+			return err
+		}
+		tm, err := tfile.ParseTime(bytes.TrimSpace(out))
+		if err != nil {
+			log.Debug("[journal]: time not found, copy whole journal", zap.Error(err))
+		}
+		from = tm
+	}
+
+	var args []string
+	if !from.IsZero() {
+		args = append(args, "--since", "@"+strconv.FormatInt(from.Add(-duration).Unix(), 10))
+	}
+	until, err := windowEnd(from)
+	if err != nil {
+		return err
+	}
+	if !until.IsZero() {
+		args = append(args, "--until", fmt.Sprintf("@%d.%06d", until.Unix(), until.Nanosecond()/int(time.Microsecond)))
+	}
+	if follow {
+		args = append(args, "--follow")
+	}
+	cmd := journalctl(args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	w, err := newWindowWriter("journal:"+flagUnit, tfile, nil)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	log.Debug("[journal]: read", zap.String("unit", flagUnit), zap.Bool("follow", follow))
+
+	buf := make([]byte, 1<<16)
+	for {
+		n, rerr := stdout.Read(buf)
+		_, err := w.Write(buf[:n])
+		if err == nil {
+			err = flushWindow(w)
+		}
+		if err == nil && rerr != io.EOF {
+			err = rerr
+		}
+		if err == errWindowEnd {
+			// lines after window are not needed, journalctl is stopped
+			stopJournalctl(cmd)
+			return closeWindow(w)
+		} else if err != nil {
+			stopJournalctl(cmd)
+			return err
+		}
+		if rerr == io.EOF {
+			break
+		}
+	}
+	if err := closeWindow(w); err != nil {
+		return err
+	}
+	return cmd.Wait()
+}
+
+// stopJournalctl kill journalctl and wait for its exit
+func stopJournalctl(cmd *exec.Cmd) {
+	if err := cmd.Process.Kill(); err != nil {
+		log.Debug("[journal]: kill journalctl", zap.Error(err))
+	}
+	cmd.Wait()
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sakateka/ttail"
+	"go.uber.org/zap"
+)
+
+var (
+	flagKubectl       string
+	flagKubeContext   string
+	flagKubeNamespace string
+	flagKubeSelector  string
+	flagKubeContainer string
+)
+
+// kubeTimeRe match time prefix added by kubectl logs --timestamps
+const kubeTimeRe = `^(\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(?:\.\d+)?(?:Z|[+-]\d\d:\d\d)) `
+
+var kubeCommand = &command{
+	name: "kube",
+	args: "[pod ...]",
+	help: "copy time window of kubernetes pods logs merged by time",
+	flags: func(fs *flag.FlagSet) {
+		fs.DurationVar(&flagDuration, "n", 10*time.Second, "offset in time to start copy (default 10s)")
+		untilFlag(fs)
+		outputFlags(fs)
+		fs.StringVar(&flagKubectl, "kubectl", "kubectl", "path to kubectl")
+		fs.StringVar(&flagKubeContext, "context", "", "kubeconfig context")
+		fs.StringVar(&flagKubeNamespace, "namespace", "", "namespace of pods")
+		fs.StringVar(&flagKubeSelector, "selector", "", "label selector of pods")
+		fs.StringVar(&flagKubeContainer, "container", "", "container name (default all containers of pod)")
+	},
+	run: runKube,
+}
+
+func kubectl(args ...string) *exec.Cmd {
+	if flagKubeContext != "" {
+		args = append(args, "--context", flagKubeContext)
+	}
+	if flagKubeNamespace != "" {
+		args = append(args, "--namespace", flagKubeNamespace)
+	}
+	cmd := exec.Command(flagKubectl, args...)
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+const (
+	kubePodJSONPath  = `{.metadata.name}{" "}{.spec.containers[*].name}{"\n"}`
+	kubeListJSONPath = `{range .items[*]}` + kubePodJSONPath + `{end}`
+)
+
+// kubeContainers return "pod/container" names of selected pods
+func kubeContainers(pods []string) ([]string, error) {
+	var out []byte
+	if flagKubeSelector != "" {
+		list, err := kubectl("get", "pods", "-l", flagKubeSelector, "-o", "jsonpath="+kubeListJSONPath).Output()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, list...)
+	}
+	for _, pod := range pods {
+		info, err := kubectl("get", "pod", pod, "-o", "jsonpath="+kubePodJSONPath).Output()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, info...)
+	}
+
+	var names []string
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		for _, container := range fields[1:] {
+			if flagKubeContainer == "" || flagKubeContainer == container {
+				names = append(names, fields[0]+"/"+container)
+			}
+		}
+	}
+	return names, nil
+}
+
+// kubeStream is a log of one container
+type kubeStream struct {
+	name  string
+	cmd   *exec.Cmd
+	r     *bufio.Reader
+	tfile *ttail.TFile
+	last  time.Time
+}
+
+func (s *kubeStream) next() (*record, error) {
+	line, err := s.r.ReadBytes('\n')
+	if len(line) == 0 && err != nil {
+		if err == io.EOF {
+			if werr := s.cmd.Wait(); werr != nil {
+				log.Error("[kube]: kubectl logs", zap.String("container", s.name), zap.Error(werr))
+			}
+		}
+		return nil, err
+	}
+	line = bytes.TrimSuffix(line, []byte{'\n'})
+	if tm, err := s.tfile.ParseTime(line); err == nil {
+		s.last = tm
+	}
+	return &record{File: s.name, Time: s.last, Line: string(line)}, nil
+}
+
+func runKube(fs *flag.FlagSet) error {
+	if fs.NArg() == 0 && flagKubeSelector == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	containers, err := kubeContainers(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	// tfile is used only to parse time of kubectl lines
+	tfile := ttail.NewTimeReader(nil, 0,
+		ttail.WithTimeReAsStr(kubeTimeRe),
+		ttail.WithTimeLayout(time.RFC3339Nano),
+	)
+	from := now()
+	since := from.Add(-flagDuration).UTC().Format(time.RFC3339)
+	until, err := windowEnd(from)
+	if err != nil {
+		return err
+	}
+	var streams []recordStream
+	for _, name := range containers {
+		idx := strings.IndexByte(name, '/')
+		cmd := kubectl("logs", name[:idx], "--container", name[idx+1:], "--timestamps", "--since-time", since)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		log.Debug("[kube]: read logs", zap.String("container", name))
+		streams = append(streams, &kubeStream{
+			name:  name,
+			cmd:   cmd,
+			r:     bufio.NewReader(stdout),
+			tfile: tfile,
+		})
+	}
+
+	lw, err := newLineWriter(os.Stdout, "", tfile, "{{.File}} {{.Line}}")
+	if err != nil {
+		return err
+	}
+	redact, err := redactFilter("")
+	if err != nil {
+		return err
+	}
+	if redact != nil {
+		lw.filters = append(lw.filters, redact)
+	}
+	write := lw.writeRecord
+	if !until.IsZero() {
+		// kubectl logs has no end of window, lines are merged in time order
+		write = func(r *record) error {
+			if r.Time.After(until) {
+				return errWindowEnd
+			}
+			return lw.writeRecord(r)
+		}
+	}
+	err = mergeRecords(streams, write)
+	if err == errWindowEnd {
+		// lines after window are not needed, kubectl is stopped
+		for _, s := range streams {
+			s.(*kubeStream).stop()
+		}
+	} else if err != nil {
+		return err
+	}
+	return closeWindow(lw)
+}
+
+func (s *kubeStream) stop() {
+	if err := s.cmd.Process.Kill(); err != nil {
+		log.Debug("[kube]: kill kubectl", zap.String("container", s.name), zap.Error(err))
+	}
+	s.cmd.Wait()
+}
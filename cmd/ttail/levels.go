@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/sakateka/ttail"
+)
+
+var flagLevels string
+
+// levels in order of severity, lines without level are counted as NONE
+var levels = []string{"FATAL", "ERROR", "WARN", "INFO", "DEBUG", "TRACE", "NONE"}
+
+// levelOf return normalized level of line by level options of log type
+func levelOf(tfile *ttail.TFile, line string) string {
+	if level := tfile.ParseLevel([]byte(line)); level != "" {
+		return level
+	}
+	return "NONE"
+}
+
+// levelSummary count lines by level per file
+type levelSummary struct {
+	files  []string
+	counts map[string]map[string]int
+}
+
+var summary = &levelSummary{counts: map[string]map[string]int{}}
+
+// output return output counting lines of file
+func (s *levelSummary) output(file string, tfile *ttail.TFile) output {
+	counts, ok := s.counts[file]
+	if !ok {
+		counts = map[string]int{}
+		s.counts[file] = counts
+		s.files = append(s.files, file)
+	}
+	return &levelsOutput{tfile: tfile, counts: counts}
+}
+
+func (s *levelSummary) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprint(tw, "FILE")
+	for _, level := range levels {
+		fmt.Fprint(tw, "\t", level)
+	}
+	fmt.Fprintln(tw)
+	for _, file := range s.files {
+		fmt.Fprint(tw, file)
+		for _, level := range levels {
+			fmt.Fprint(tw, "\t", s.counts[file][level])
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func (s *levelSummary) writeJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s.counts)
+}
+
+// levelsOutput count lines by level instead of writing them
+type levelsOutput struct {
+	tfile  *ttail.TFile
+	counts map[string]int
+}
+
+func (o *levelsOutput) write(r *record) error {
+	o.counts[levelOf(o.tfile, r.Line)]++
+	return nil
+}
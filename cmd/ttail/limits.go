@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// limiterBuckets is a number of remembered clients after which
+// buckets of idle clients are dropped
+const limiterBuckets = 10000
+
+var (
+	flagMaxSearches int
+	flagMaxBytes    int64
+	flagRate        float64
+	flagBurst       int
+)
+
+// errResponseLimit returned by limitWriter when response is too large
+var errResponseLimit = errors.New("response size limit exceeded")
+
+// limitWriter fail writes after n bytes
+type limitWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.n {
+		n, err := l.w.Write(p[:l.n])
+		l.n -= int64(n)
+		if err == nil {
+			err = errResponseLimit
+		}
+		return n, err
+	}
+	n, err := l.w.Write(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// bucket of tokens of one client
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter of concurrent searches and of request rate per client address
+type limiter struct {
+	searches chan struct{}
+	rate     float64
+	burst    float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newLimiter(maxSearches int, rate float64, burst int) *limiter {
+	l := &limiter{rate: rate, burst: float64(burst), buckets: map[string]*bucket{}}
+	if maxSearches > 0 {
+		l.searches = make(chan struct{}, maxSearches)
+	}
+	if l.burst < 1 {
+		l.burst = 1
+	}
+	return l
+}
+
+// allow take token of client, tokens are refilled at rate per second up to burst
+func (l *limiter) allow(client string) bool {
+	if l.rate <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tm := time.Now()
+	if len(l.buckets) >= limiterBuckets {
+		for c, b := range l.buckets {
+			if b.tokens+tm.Sub(b.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, c)
+			}
+		}
+	}
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: tm}
+		l.buckets[client] = b
+	}
+	b.tokens += tm.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = tm
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// acquire slot of search, false if all slots are busy
+func (l *limiter) acquire() bool {
+	if l.searches == nil {
+		return true
+	}
+	select {
+	case l.searches <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *limiter) release() {
+	if l.searches != nil {
+		<-l.searches
+	}
+}
+
+// wrap reject requests over rate of client with 429 and over
+// concurrent searches with 503
+func (l *limiter) wrap(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		if !l.allow(client) {
+			metricQueries.add("limited", 1)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		if !l.acquire() {
+			metricQueries.add("limited", 1)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "too many concurrent searches", http.StatusServiceUnavailable)
+			return
+		}
+		defer l.release()
+		h(w, r)
+	}
+}
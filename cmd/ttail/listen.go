@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation
+const listenFDsStart = 3
+
+// listen return socket passed by systemd (LISTEN_FDS) or listen addr
+func listen(addr string) (net.Listener, bool, error) {
+	pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	fds, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if pid != os.Getpid() || fds < 1 {
+		l, err := net.Listen("tcp", addr)
+		return l, false, err
+	}
+	// children must not take the socket
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	file := os.NewFile(listenFDsStart, "LISTEN_FD_3")
+	l, err := net.FileListener(file)
+	file.Close()
+	return l, true, err
+}
+
+// idleTracker count active requests to stop idle server
+type idleTracker struct {
+	mu     sync.Mutex
+	active int
+	last   time.Time
+}
+
+func (t *idleTracker) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.mu.Lock()
+		t.active++
+		t.mu.Unlock()
+		defer func() {
+			t.mu.Lock()
+			t.active--
+			t.last = time.Now()
+			t.mu.Unlock()
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// idle report whether there were no requests for d
+func (t *idleTracker) idle(d time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.active == 0 && time.Since(t.last) >= d
+}
+
+// serveGraceful serve h until SIGINT or SIGTERM or idle timeout,
+// active requests are drained for drain duration
+func serveGraceful(addr string, h http.Handler, drain, exitIdle time.Duration) error {
+	l, activated, err := listen(addr)
+	if err != nil {
+		return err
+	}
+	log.Debug("[serve]: listen", zap.String("addr", l.Addr().String()), zap.Bool("socketActivated", activated))
+
+	tracker := &idleTracker{last: time.Now()}
+	srv := &http.Server{Handler: tracker.wrap(h)}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	done := make(chan error, 1)
+	go func() {
+		var idle <-chan time.Time
+		if exitIdle > 0 {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			idle = ticker.C
+		}
+		for {
+			select {
+			case sig := <-stop:
+				log.Debug("[serve]: shutdown", zap.String("signal", sig.String()))
+			case <-idle:
+				if !tracker.idle(exitIdle) {
+					continue
+				}
+				log.Debug("[serve]: shutdown idle server", zap.Duration("idle", exitIdle))
+			}
+			break
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), drain)
+		defer cancel()
+		done <- srv.Shutdown(ctx)
+	}()
+	if err := srv.Serve(l); err != http.ErrServerClosed {
+		return err
+	}
+	return <-done
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	stdLog "log"
@@ -17,25 +20,97 @@ var log *zap.Logger
 var flagTimeFromLastLine bool
 var flagLogType string
 var flagDuration time.Duration
+var flagNow string
+var flagSkew time.Duration
+var flagDescending bool
+var flagDST string
+var flagParseFailure string
+var flagMinLevel string
 
-func init() {
-	flag.Usage = func() {
-		_, _ = os.Stderr.WriteString("Usage of " + os.Args[0] + " [options] file [file ...]:\n")
-		flag.PrintDefaults()
+// now return current time, it is fixed by -now
+var now = time.Now
+
+// command is a ttail subcommand
+type command struct {
+	name  string
+	args  string
+	help  string
+	flags func(fs *flag.FlagSet)
+	run   func(fs *flag.FlagSet) error
+}
+
+var commands = []*command{
+	auditCommand,
+	benchCommand,
+	catCommand,
+	fleetCommand,
+	followCommand,
+	indexCommand,
+	kubeCommand,
+	serveCommand,
+	shipCommand,
+	typesCommand,
+	validateCommand,
+}
+
+// defaultCommand used for the bare legacy invocation: ttail [options] file ...
+var defaultCommand = catCommand
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
 	}
-	flag.DurationVar(&flagDuration, "n", 10*time.Second, "offset in time to start copy (default 10s)")
-	flag.BoolVar(&flagTimeFromLastLine, "l", false, "tail last N secconds from time in last line (default from time.Now())")
-	flag.StringVar(&flagLogType, "t", "", "use a type of log (default tskv)")
-	flag.BoolVar(&ttail.FlagDebug, "d", false, "set Debug mode")
+	return nil
 }
 
-func main() {
-	flag.Parse()
-	if flag.NArg() == 0 {
-		flag.Usage()
-		os.Exit(1)
+func usage() {
+	_, _ = os.Stderr.WriteString("Usage of " + os.Args[0] + " [command] [options] [args]:\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.help)
 	}
+	_, _ = os.Stderr.WriteString("\nWithout command '" + defaultCommand.name + "' is used, options of '" + defaultCommand.name + "':\n")
+	fs := newFlagSet(defaultCommand)
+	fs.PrintDefaults()
+}
+
+func newFlagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = os.Stderr.WriteString("Usage of " + os.Args[0] + " " + cmd.name + " [options] " + cmd.args + ":\n")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&ttail.FlagDebug, "d", false, "set Debug mode")
+	fs.StringVar(&ttail.DefaultConfigFile, "c", ttail.DefaultConfigFile, "path to config file with log types")
+	profileFlags(fs)
+	fs.StringVar(&flagErrorFormat, "error-format", "text", "format of per file errors on stderr: text or json")
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+	return fs
+}
+
+// windowFlags add options of time window search
+func windowFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&flagDuration, "n", 10*time.Second, "offset in time to start copy (default 10s)")
+	fs.BoolVar(&flagTimeFromLastLine, "l", false, "tail last N secconds from time in last line (default from time.Now())")
+	fs.StringVar(&flagLogType, "t", "", "use a type of log, auto detects type by first lines of file (default tskv)")
+	fs.StringVar(&flagSince, "since", "", "start time window at RFC3339 time or duration before now instead of -n")
+	fs.BoolVar(&flagDescending, "desc", false, "log is written newest first, window is copied in time order")
+	fs.StringVar(&flagDST, "dst", "", "time of local wall clock ambiguous or skipped by daylight saving: earliest, latest or utc (default type dst)")
+	fs.StringVar(&flagParseFailure, "on-parse-failure", "", "lines of time window without time: include, skip or stop (default type onParseFailure or include)")
+	fs.StringVar(&flagMinLevel, "min-level", "", "copy only lines at or above level, e.g. error, lines without time follow the previous line")
+	fs.DurationVar(&flagSkew, "skew", 0, "widen time window by clock skew of log writers")
+	fs.StringVar(&flagNow, "now", "", "evaluate time window relative to RFC3339 time or duration before now instead of current time")
+}
+
+// untilFlag register end of time window, followed files have no end
+func untilFlag(fs *flag.FlagSet) {
+	fs.StringVar(&flagUntil, "until", "", "end time window at RFC3339 time or duration before now or last line with -l, lines after it are not copied")
+}
 
+func initLogger() {
 	cfg := zap.NewProductionConfig()
 	cfg.Level.SetLevel(zapcore.ErrorLevel)
 	if ttail.FlagDebug {
@@ -46,49 +121,186 @@ func main() {
 	if err != nil {
 		stdLog.Fatalf("can't initialize zap logger: %v", err)
 	}
+}
 
-	var file *os.File
-	var fileInfo os.FileInfo
-	for _, fname := range flag.Args() {
-		if file != nil {
-			file.Close()
-			file = nil
-		}
-		log.Debug("[main]: process file", zap.String("fileName", fname))
+// splitFileDuration parse "file.log:30m" argument into file name
+// and per file tail duration, zero duration means use -n value
+func splitFileDuration(arg string) (string, time.Duration) {
+	idx := strings.LastIndexByte(arg, ':')
+	if idx < 0 {
+		return arg, 0
+	}
+	if _, err := os.Stat(arg); err == nil {
+		// file name itself contains ':'
+		return arg, 0
+	}
+	d, err := time.ParseDuration(arg[idx+1:])
+	if err != nil || d <= 0 {
+		return arg, 0
+	}
+	return arg[:idx], d
+}
+
+// typeConfig return config of log type
+func typeConfig(logType string) (ttail.Type, error) {
+	conf, err := ttail.DefaultConfig()
+	if err != nil {
+		return ttail.Type{}, err
+	}
+	aType, ok := conf[logType]
+	if !ok {
+		return ttail.Type{}, errors.New("Failed to find options for log type: " + logType)
+	}
+	return aType, nil
+}
 
-		fileInfo, err = os.Stat(fname)
+// openTimeFile open file or url and search the start of time window in it,
+// on io.EOF (no lines in time window) source is returned too
+func openTimeFile(fname string, duration time.Duration, opts ...ttail.TimeFileOptions) (source, *ttail.TFile, error) {
+	return openTimeSource(fname, flagLogType, append(windowOptions(duration), opts...))
+}
+
+// windowOptions return options of time window search set by flags
+func windowOptions(duration time.Duration) []ttail.TimeFileOptions {
+	opts := []ttail.TimeFileOptions{
+		ttail.WithTimeFromLastLine(flagTimeFromLastLine),
+		ttail.WithDuration(duration),
+		ttail.WithClock(now),
+		ttail.WithSkewTolerance(flagSkew),
+	}
+	if flagLogType != "" && flagLogType != autoLogType {
+		logOpts, err := ttail.OptionsFromConfig(flagLogType)
 		if err != nil {
-			log.Error("[main]: file stat", zap.String("logname", fname), zap.Error(err))
-			continue
-		} else if fileInfo.IsDir() {
-			log.Error("[main]: skip directory!", zap.String("name", fname))
-			continue
+			log.Fatal("Failed to get ttail options from config", zap.Error(err))
 		}
-		file, err = os.Open(fname)
+		opts = append(opts, logOpts...)
+	}
+	if flagDescending {
+		opts = append(opts, ttail.WithDescending(true))
+	}
+	if flagDST != "" {
+		if err := (ttail.Type{DST: flagDST}).Validate(); err != nil {
+			log.Fatal("Failed to parse -dst", zap.Error(err))
+		}
+		opts = append(opts, ttail.WithDSTPolicy(ttail.DSTPolicy(flagDST)))
+	}
+	if flagParseFailure != "" {
+		if err := (ttail.Type{OnParseFailure: flagParseFailure}).Validate(); err != nil {
+			log.Fatal("Failed to parse -on-parse-failure", zap.Error(err))
+		}
+		opts = append(opts, ttail.WithOnParseFailure(ttail.ParseFailurePolicy(flagParseFailure)))
+	}
+	if flagMinLevel != "" {
+		if ttail.LevelSeverity(flagMinLevel) == 0 {
+			log.Fatal("Failed to parse -min-level, want fatal, error, warn, info, debug or trace", zap.String("level", flagMinLevel))
+		}
+		opts = append(opts, ttail.WithMinLevel(flagMinLevel))
+	}
+	if flagSince != "" {
+		since, err := parseTimeArg(flagSince)
 		if err != nil {
-			log.Error("[main]: skip", zap.String("logname", fname), zap.Error(err))
-			continue
+			log.Fatal("Failed to parse -since", zap.Error(err))
 		}
-		opts := []ttail.TimeFileOptions{
-			ttail.WithTimeFromLastLine(flagTimeFromLastLine),
-			ttail.WithDuration(flagDuration),
+		opts = append(opts, ttail.WithSince(since))
+	} else if flagHead != "" {
+		// head of file by time starts at the first line with time
+		opts = append(opts, ttail.WithSince(time.Unix(0, 0)))
+	}
+	if d, err := time.ParseDuration(flagUntil); err == nil {
+		// duration is counted back from now or the last line with -l like -n
+		opts = append(opts, ttail.WithEndDuration(d))
+	} else if flagUntil != "" {
+		until, err := time.Parse(time.RFC3339, flagUntil)
+		if err != nil {
+			log.Fatal("Failed to parse -until", zap.Error(err))
 		}
-		if flagLogType != "" {
-			logOpts, err := ttail.OptionsFromConfig(flagLogType)
-			if err != nil {
-				log.Fatal("Failed to get ttail options from config", zap.Error(err))
-			}
-			opts = append(opts, logOpts...)
+		opts = append(opts, ttail.WithUntil(until))
+	}
+	return opts
+}
+
+// openTimeSource open file or url and search the start of time window
+// with given options of logType, on io.EOF source is returned too
+func openTimeSource(fname, logType string, opts []ttail.TimeFileOptions) (source, *ttail.TFile, error) {
+	src, size, err := openSource(fname, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if typed, ok := src.(typedSource); ok {
+		opts = append(opts, typed.options()...)
+	} else if logType == autoLogType {
+		opts = append(opts, detectLogType(fname, src, size)...)
+		logType = fileLogType(fname, logType)
+	}
+	if _, ok := src.(*os.File); ok {
+		if opt := loadIndex(fname); opt != nil {
+			opts = append(opts, opt)
 		}
-		tfile := ttail.NewTimeFile(file, opts...)
-
-		if err := tfile.FindPosition(); err != nil {
-			if err != io.EOF {
-				log.Fatal("[main]: error", zap.Error(err))
-			}
-			log.Debug("[main]: findPosition got EOF")
-			continue
+	}
+	tfile := ttail.NewTimeReader(src, size, opts...)
+	if headFromStart() {
+		tfile.SetOffset(0)
+		return src, tfile, nil
+	}
+
+	err = tfile.FindPosition()
+	observeSearch(logType, tfile)
+	if err != nil {
+		if err == io.EOF {
+			return src, tfile, err
 		}
-		_, _ = tfile.CopyTo(os.Stdout)
+		src.Close()
+		return nil, nil, err
+	}
+	return src, tfile, nil
+}
+
+// fixNow set time of now by -now
+func fixNow() error {
+	if flagNow == "" {
+		return nil
+	}
+	tm, err := parseTimeArg(flagNow)
+	if err != nil {
+		return err
+	}
+	now = func() time.Time { return tm }
+	return nil
+}
+
+func main() {
+	cmd, args := defaultCommand, os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			usage()
+			return
+		}
+		if c := findCommand(args[0]); c != nil {
+			cmd, args = c, args[1:]
+		}
+	} else {
+		usage()
+		os.Exit(1)
+	}
+
+	fs := newFlagSet(cmd)
+	_ = fs.Parse(args)
+	commandName = cmd.name
+	initLogger()
+	if flagErrorFormat != "text" && flagErrorFormat != "json" {
+		log.Fatal("[main]: bad -error-format, want text or json", zap.String("format", flagErrorFormat))
+	}
+	if err := fixNow(); err != nil {
+		log.Fatal("[main]: bad -now", zap.Error(err))
+	}
+	stopProfile, err := startProfile()
+	if err != nil {
+		log.Fatal("[main]: profile", zap.Error(err))
+	}
+	err = cmd.run(fs)
+	stopProfile()
+	if err != nil {
+		log.Fatal("[main]: "+cmd.name, zap.Error(err))
 	}
 }
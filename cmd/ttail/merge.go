@@ -0,0 +1,68 @@
+package main
+
+import (
+	"container/heap"
+	"io"
+)
+
+// recordStream is a stream of records sorted by time
+type recordStream interface {
+	next() (*record, error)
+}
+
+type mergeItem struct {
+	r      *record
+	stream int
+}
+
+// mergeHeap order records by time, records of the same time
+// keep order of streams
+type mergeHeap []mergeItem
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].r.Time.Equal(h[j].r.Time) {
+		return h[i].stream < h[j].stream
+	}
+	return h[i].r.Time.Before(h[j].r.Time)
+}
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeItem)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// mergeRecords write records of sorted streams in order of time
+func mergeRecords(streams []recordStream, write func(r *record) error) error {
+	h := make(mergeHeap, 0, len(streams))
+	for i, s := range streams {
+		r, err := s.next()
+		if err == io.EOF {
+			continue
+		} else if err != nil {
+			return err
+		}
+		h = append(h, mergeItem{r: r, stream: i})
+	}
+	heap.Init(&h)
+
+	for h.Len() > 0 {
+		item := h[0]
+		if err := write(item.r); err != nil {
+			return err
+		}
+		r, err := streams[item.stream].next()
+		if err == io.EOF {
+			heap.Pop(&h)
+			continue
+		} else if err != nil {
+			return err
+		}
+		h[0].r = r
+		heap.Fix(&h, 0)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/sakateka/ttail"
+	"go.uber.org/zap"
+)
+
+var flagMetricsAddr string
+
+func metricsFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagMetricsAddr, "metrics", "", "address to serve prometheus /metrics")
+}
+
+// metric in prometheus text format
+type metric interface {
+	writeTo(w io.Writer)
+}
+
+var metrics []metric
+
+// counterVec is a counter with one label
+type counterVec struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	label  string
+	values map[string]float64
+}
+
+func newCounterVec(name, help, label string) *counterVec {
+	c := &counterVec{name: name, help: help, label: label, values: map[string]float64{}}
+	metrics = append(metrics, c)
+	return c
+}
+
+func (c *counterVec) add(label string, v float64) {
+	c.mu.Lock()
+	c.values[label] += v
+	c.mu.Unlock()
+}
+
+func (c *counterVec) writeTo(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	labels := make([]string, 0, len(c.values))
+	for label := range c.values {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		if c.label == "" {
+			fmt.Fprintf(w, "%s %v\n", c.name, c.values[label])
+			continue
+		}
+		fmt.Fprintf(w, "%s{%s=%q} %v\n", c.name, c.label, label, c.values[label])
+	}
+}
+
+// histogram of observed values
+type histogram struct {
+	mu      sync.Mutex
+	name    string
+	help    string
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(name, help string, buckets ...float64) *histogram {
+	h := &histogram{name: name, help: help, buckets: buckets, counts: make([]uint64, len(buckets))}
+	metrics = append(metrics, h)
+	return h
+}
+
+func (h *histogram) observe(v float64) {
+	h.mu.Lock()
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+func (h *histogram) writeTo(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for i, b := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", h.name, strconv.FormatFloat(b, 'g', -1, 64), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(w, "%s_sum %v\n%s_count %d\n", h.name, h.sum, h.name, h.count)
+}
+
+var (
+	metricQueries      = newCounterVec("ttail_queries_total", "Served queries by status.", "status")
+	metricSearches     = newCounterVec("ttail_searches_total", "Time searches by log type.", "type")
+	metricReadBytes    = newCounterVec("ttail_search_read_bytes_total", "Bytes read by time search.", "")
+	metricEmittedBytes = newCounterVec("ttail_emitted_bytes_total", "Bytes written to output.", "")
+	metricLines        = newCounterVec("ttail_lines_total", "Emitted lines by log type.", "type")
+	metricParseFails   = newCounterVec("ttail_parse_failures_total", "Emitted lines without time by log type.", "type")
+	metricProbes       = newHistogram("ttail_search_probes", "Binary search steps per time search.",
+		1, 2, 4, 8, 16, 32, 64)
+)
+
+// metricType return label value for log type
+func metricType(logType string) string {
+	if logType == "" {
+		return "default"
+	}
+	return logType
+}
+
+// observeSearch account statistics of finished time search
+func observeSearch(logType string, tfile *ttail.TFile) {
+	stats := tfile.Stats()
+	metricSearches.add(metricType(logType), 1)
+	metricReadBytes.add("", float64(stats.BytesRead))
+	metricProbes.observe(float64(stats.Probes))
+}
+
+// countWriter account bytes written to output
+type countWriter struct {
+	w io.Writer
+}
+
+func (c countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	metricEmittedBytes.add("", float64(n))
+	return n, err
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, m := range metrics {
+		m.writeTo(w)
+	}
+}
+
+// startMetrics serve /metrics in background if -metrics is set
+func startMetrics() {
+	if flagMetricsAddr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", serveMetrics)
+	go func() {
+		if err := http.ListenAndServe(flagMetricsAddr, mux); err != nil {
+			log.Error("[metrics]: listen", zap.String("addr", flagMetricsAddr), zap.Error(err))
+		}
+	}()
+}
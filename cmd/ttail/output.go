@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sakateka/ttail"
+)
+
+// record is a line of time window passed to output
+type record struct {
+	File   string
+	Time   time.Time
+	Line   string
+	Fields map[string]string
+}
+
+// output write records of time window
+type output interface {
+	write(r *record) error
+}
+
+// filter modify record in place, false means drop the record
+type filter func(r *record) bool
+
+// rawOutput write lines as is
+type rawOutput struct {
+	w io.Writer
+}
+
+func (o rawOutput) write(r *record) error {
+	if _, err := io.WriteString(o.w, r.Line); err != nil {
+		return err
+	}
+	_, err := o.w.Write([]byte{'\n'})
+	return err
+}
+
+func outputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagFormat, "format", "", "go template for output lines, fields: .File .Time .Line .Fields")
+	fs.StringVar(&flagOutput, "output", "", "output format instead of lines: csv of -columns or es-bulk body of elasticsearch bulk api")
+	fs.StringVar(&flagColumns, "columns", "", "comma separated fields of type for -output csv, also @time, @file, @line (default @time and all fields)")
+	fs.StringVar(&flagTZ, "tz", "", "rewrite time of lines into time zone, e.g. UTC or Europe/Moscow")
+	fs.Var(&flagRedact, "redact", "replace regexp=replacement in every line, replacement follows the last '=' (repeatable)")
+	fs.StringVar(&flagSample, "sample", "", "output only sample of lines: probability 0.01 or every Nth line 1/N")
+	fs.Int64Var(&flagSeed, "seed", 0, "random seed of -sample for reproducible output (default random)")
+	fs.StringVar(&flagTraceID, "trace-id", "", "output only lines of trace with trace_id")
+	fs.BoolVar(&flagDedup, "dedup", false, "fold repeated lines differing only in time into one line")
+	fs.StringVar(&flagLoki, "loki", "", "push lines to loki push api url instead of stdout, e.g. http://localhost:3100/loki/api/v1/push")
+	fs.StringVar(&flagLokiLabels, "loki-labels", "", "extra loki stream labels: key=value,key=value")
+	fs.StringVar(&flagES, "es", "", "push lines to elasticsearch bulk api at url instead of stdout, e.g. http://localhost:9200")
+	fs.StringVar(&flagESIndex, "es-index", "ttail", "elasticsearch index of -es and -output es-bulk")
+}
+
+// lineMode report whether output needs processing of separate lines
+func lineMode() bool {
+	return flagFormat != "" || flagTZ != "" || flagMetricsAddr != "" || flagLoki != "" || flagES != "" || flagReplay || flagSample != "" ||
+		flagDedup || flagTraceID != "" || flagNow != "" || flagHead != "" || flagLevels != "" || flagOutput != "" || flagGaps > 0 || flagSplit > 0
+}
+
+// lineWriter split written data into lines and pass them to output
+type lineWriter struct {
+	file    string
+	logType string
+	tfile   *ttail.TFile
+	buf     *bufio.Writer
+	out     output
+	filters []filter
+	pending []byte
+	last    time.Time
+	until   time.Time
+	// head of time window by time span or number of lines
+	head      time.Duration
+	headLines int
+	lines     int
+}
+
+// errWindowEnd returned by lineWriter when line after until time is written
+var errWindowEnd = errors.New("end of time window")
+
+// newWindowWriter return writer for time window of file
+func newWindowWriter(fname string, tfile *ttail.TFile, src source) (io.Writer, error) {
+	logType := fileLogType(fname, flagLogType)
+	redact, err := redactFilter(logType)
+	if err != nil {
+		return nil, err
+	}
+	filtered, ok := src.(filteredSource)
+	if !ok && redact == nil && !lineMode() {
+		return os.Stdout, nil
+	}
+	lw, err := newLineWriter(os.Stdout, fname, tfile, "")
+	if err != nil {
+		return nil, err
+	}
+	lw.logType = logType
+	if flagNow != "" && !flagTimeFromLastLine {
+		// window ends at the fixed current time
+		lw.until = now().Add(flagSkew)
+	}
+	if flagHead != "" {
+		if lw.head, lw.headLines, err = parseHead(flagHead); err != nil {
+			return nil, err
+		}
+	}
+	if ok {
+		if f := filtered.filter(); f != nil {
+			// lines of source are unwrapped before other filters,
+			// so they are filtered like lines of files
+			lw.filters = append([]filter{f}, lw.filters...)
+		}
+	}
+	if redact != nil {
+		lw.filters = append(lw.filters, redact)
+	}
+	if logType != "" {
+		aType, err := typeConfig(logType)
+		if err != nil {
+			return nil, err
+		}
+		parse, err := aType.FieldParser()
+		if err != nil {
+			return nil, err
+		}
+		if parse != nil {
+			lw.filters = append(lw.filters, fieldsFilter(parse))
+		}
+	}
+	return lw, nil
+}
+
+// newLineWriter return writer which process every line of time window,
+// format is used if -format is not set, empty format means raw lines
+func newLineWriter(w io.Writer, fname string, tfile *ttail.TFile, format string) (*lineWriter, error) {
+	lw := &lineWriter{
+		file:  fname,
+		tfile: tfile,
+		buf:   bufio.NewWriter(countWriter{w}),
+	}
+	lw.out = rawOutput{w: lw.buf}
+	if flagFormat != "" {
+		format = flagFormat
+	}
+	if format != "" {
+		out, err := newTemplateOutput(lw.buf, format)
+		if err != nil {
+			return nil, err
+		}
+		lw.out = out
+	}
+	switch flagOutput {
+	case "":
+	case "csv":
+		lw.out = newCSVOutput(lw.buf, flagColumns)
+	case "es-bulk":
+		lw.out = &esBulkOutput{w: lw.buf, index: flagESIndex}
+	default:
+		return nil, errors.New("unknown output: " + flagOutput)
+	}
+	sink, err := newSinkOutput()
+	if err != nil {
+		return nil, err
+	}
+	if sink != nil {
+		lw.out = sink
+	}
+	if flagLevels != "" {
+		lw.out = summary.output(fname, tfile)
+	}
+	if flagGaps > 0 {
+		lw.out = &gapsOutput{w: lw.buf, threshold: flagGaps}
+	}
+	if flagSplit > 0 {
+		lw.out = newSplitOutput(fname)
+	}
+	if flagReplay {
+		lw.out = &replayOutput{out: lw.out, flush: lw.buf.Flush}
+	}
+	if flagDedup {
+		lw.out = &dedupOutput{out: lw.out, tfile: tfile}
+	}
+	if trace := traceFilter(tfile, flagTraceID); trace != nil {
+		lw.filters = append(lw.filters, trace)
+	}
+	if flagTZ != "" {
+		loc, err := time.LoadLocation(flagTZ)
+		if err != nil {
+			return nil, err
+		}
+		lw.filters = append(lw.filters, tzFilter(tfile, loc))
+	}
+	sample, err := sampleFilter(flagSample, flagSeed)
+	if err != nil {
+		return nil, err
+	}
+	if sample != nil {
+		lw.filters = append(lw.filters, sample)
+	}
+	return lw, nil
+}
+
+// flushWindow write buffered lines of window writer
+func flushWindow(w io.Writer) error {
+	if lw, ok := w.(*lineWriter); ok {
+		if f, ok := lw.out.(flusher); ok {
+			if err := f.flush(); err != nil {
+				return err
+			}
+		}
+		return lw.buf.Flush()
+	}
+	return nil
+}
+
+// closeWindow write incomplete last line and flush window writer
+func closeWindow(w io.Writer) error {
+	if lw, ok := w.(*lineWriter); ok && len(lw.pending) > 0 {
+		err := lw.writeLine(lw.pending)
+		lw.pending = lw.pending[:0]
+		if err != nil && err != errWindowEnd {
+			return err
+		}
+	}
+	return flushWindow(w)
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		idx := bytes.IndexByte(p, '\n')
+		if idx < 0 {
+			lw.pending = append(lw.pending, p...)
+			break
+		}
+		line := p[:idx]
+		if len(lw.pending) > 0 {
+			line = append(lw.pending, line...)
+		}
+		if err := lw.writeLine(line); err != nil {
+			lw.pending = lw.pending[:0]
+			return n - len(p), err
+		}
+		lw.pending = lw.pending[:0]
+		p = p[idx+1:]
+	}
+	return n, nil
+}
+
+func (lw *lineWriter) writeLine(line []byte) error {
+	// lines without time (e.g. stack traces) belong to the previous line time
+	metricLines.add(metricType(lw.logType), 1)
+	if tm, err := lw.tfile.ParseTime(line); err == nil {
+		lw.last = tm
+	} else {
+		metricParseFails.add(metricType(lw.logType), 1)
+	}
+	if lw.head > 0 && !lw.last.IsZero() {
+		// head ends window after the first line time unless until is earlier
+		if end := lw.last.Add(lw.head); lw.until.IsZero() || end.Before(lw.until) {
+			lw.until = end
+		}
+		lw.head = 0
+	}
+	if !lw.until.IsZero() && lw.last.After(lw.until) {
+		return errWindowEnd
+	}
+	if lw.headLines > 0 {
+		if lw.lines >= lw.headLines {
+			return errWindowEnd
+		}
+		lw.lines++
+	}
+	return lw.writeRecord(&record{
+		File: lw.file,
+		Time: lw.last,
+		Line: string(line),
+	})
+}
+
+// writeRecord pass record through filters to output
+func (lw *lineWriter) writeRecord(r *record) error {
+	for _, f := range lw.filters {
+		if !f(r) {
+			return nil
+		}
+	}
+	return lw.out.write(r)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sakateka/ttail"
+)
+
+func TestLineWriterWindow(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	var data bytes.Buffer
+	for i := 0; i < 10; i++ {
+		tm := start.Add(time.Duration(i) * time.Second)
+		fmt.Fprintf(&data, "tskv\ttimestamp=%s\tn=%d\n", tm.Format("2006-01-02T15:04:05"), i)
+		if i == 1 {
+			// line without time belongs to the previous line
+			data.WriteString("\tat stack trace\n")
+		}
+	}
+	tests := []struct {
+		name      string
+		head      time.Duration
+		headLines int
+		until     time.Time
+		lines     int
+		end       bool
+	}{
+		{name: "whole", lines: 11},
+		{name: "until", until: start.Add(4 * time.Second), lines: 6, end: true},
+		{name: "head", head: 3 * time.Second, lines: 5, end: true},
+		{name: "head after until", head: 3 * time.Second, until: start.Add(time.Second), lines: 3, end: true},
+		{name: "head before until", head: 2 * time.Second, until: start.Add(5 * time.Second), lines: 4, end: true},
+		{name: "head lines", headLines: 3, lines: 3, end: true},
+		{name: "head lines after until", headLines: 8, until: start.Add(2 * time.Second), lines: 4, end: true},
+		{name: "head lines over window", headLines: 20, lines: 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			lw := &lineWriter{
+				tfile:     ttail.NewTimeReader(nil, 0, ttail.WithLocation(time.UTC)),
+				buf:       bufio.NewWriter(&out),
+				head:      tt.head,
+				headLines: tt.headLines,
+				until:     tt.until,
+			}
+			lw.out = rawOutput{w: lw.buf}
+			_, err := lw.Write(data.Bytes())
+			if (err == errWindowEnd) != tt.end || (err != nil && err != errWindowEnd) {
+				t.Errorf("Write: %v, want window end %v", err, tt.end)
+			}
+			if err := closeWindow(lw); err != nil {
+				t.Fatalf("closeWindow: %v", err)
+			}
+			if lines := strings.Count(out.String(), "\n"); lines != tt.lines {
+				t.Errorf("%d lines, want %d:\n%s", lines, tt.lines, out.String())
+			}
+		})
+	}
+}
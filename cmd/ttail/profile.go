@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"go.uber.org/zap"
+)
+
+var (
+	flagCPUProfile string
+	flagMemProfile string
+)
+
+func profileFlags(fs *flag.FlagSet) {
+	fs.StringVar(&flagCPUProfile, "cpuprofile", "", "write cpu profile to file")
+	fs.StringVar(&flagMemProfile, "memprofile", "", "write memory profile to file on exit")
+}
+
+// startProfile start cpu profile if it is requested,
+// returned stop function writes requested profiles
+func startProfile() (func(), error) {
+	var cpu *os.File
+	if flagCPUProfile != "" {
+		var err error
+		if cpu, err = os.Create(flagCPUProfile); err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(cpu); err != nil {
+			cpu.Close()
+			return nil, err
+		}
+	}
+	return func() {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			cpu.Close()
+		}
+		if flagMemProfile == "" {
+			return
+		}
+		f, err := os.Create(flagMemProfile)
+		if err != nil {
+			log.Error("[profile]: memprofile", zap.Error(err))
+			return
+		}
+		defer f.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			log.Error("[profile]: memprofile", zap.Error(err))
+		}
+	}, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// progressMinSize is a window size to show progress bar for
+	progressMinSize = 64 << 20
+	progressWidth   = 30
+	progressRefresh = 200 * time.Millisecond
+)
+
+// progressBar render copied bytes of time window on stderr
+type progressBar struct {
+	name     string
+	rendered time.Time
+}
+
+// newProgressBar return progress bar of file, nil if stderr is not a terminal
+// or lines are written to terminal too
+func newProgressBar(name string) *progressBar {
+	if !isTerminal(os.Stderr) || isTerminal(os.Stdout) {
+		return nil
+	}
+	return &progressBar{name: name}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *progressBar) update(copied, total int64) {
+	if total < progressMinSize || time.Since(p.rendered) < progressRefresh {
+		return
+	}
+	p.rendered = time.Now()
+	ratio := float64(copied) / float64(total)
+	if ratio > 1 {
+		ratio = 1
+	}
+	filled := int(ratio * progressWidth)
+	fmt.Fprintf(os.Stderr, "\r%s [%s%s] %3.0f%% %s/%s", p.name,
+		strings.Repeat("=", filled), strings.Repeat(" ", progressWidth-filled),
+		ratio*100, humanBytes(copied), humanBytes(total))
+}
+
+// done clear progress bar line
+func (p *progressBar) done() {
+	if p == nil || p.rendered.IsZero() {
+		return
+	}
+	fmt.Fprint(os.Stderr, "\r\033[K")
+}
+
+// progressReader report bytes read of time window to progress bar
+type progressReader struct {
+	r     io.Reader
+	read  int64
+	total int64
+	bar   *progressBar
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	p.bar.update(p.read, p.total)
+	return n, err
+}
+
+// humanBytes format size with binary unit
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
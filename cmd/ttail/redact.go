@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/sakateka/ttail"
+)
+
+// stringList is a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(arg string) error {
+	*l = append(*l, arg)
+	return nil
+}
+
+var flagRedact stringList
+
+// redactFilter return filter applying -redact rules and rules of logType,
+// nil if there are no rules
+func redactFilter(logType string) (filter, error) {
+	var rules []ttail.Redaction
+	for _, rule := range flagRedact {
+		r, err := ttail.ParseRedaction(rule)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	if logType != "" {
+		aType, err := typeConfig(logType)
+		if err != nil {
+			return nil, err
+		}
+		typeRules, err := aType.Redactions()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, typeRules...)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return func(r *record) bool {
+		for _, rule := range rules {
+			r.Line = rule.Re.ReplaceAllString(r.Line, rule.Replacement)
+		}
+		return true
+	}, nil
+}
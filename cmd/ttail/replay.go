@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	flagReplay bool
+	flagSpeed  = speedValue(1)
+)
+
+// speedValue is a replay speed multiplier: 10, 10x or 0.5x
+type speedValue float64
+
+func (s *speedValue) String() string {
+	return strconv.FormatFloat(float64(*s), 'g', -1, 64) + "x"
+}
+
+func (s *speedValue) Set(arg string) error {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(arg, "x"), 64)
+	if err != nil {
+		return err
+	}
+	if v <= 0 {
+		return fmt.Errorf("speed must be positive: %s", arg)
+	}
+	*s = speedValue(v)
+	return nil
+}
+
+func replayFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&flagReplay, "replay", false, "emit lines paced by gaps between their times")
+	fs.Var(&flagSpeed, "speed", "replay speed multiplier, e.g. 10x")
+}
+
+// replayOutput delay records according to their times
+// and write every record immediately
+type replayOutput struct {
+	out       output
+	flush     func() error
+	firstTime time.Time
+	firstWall time.Time
+}
+
+func (o *replayOutput) write(r *record) error {
+	if !r.Time.IsZero() {
+		if o.firstTime.IsZero() {
+			o.firstTime, o.firstWall = r.Time, time.Now()
+		}
+		offset := time.Duration(float64(r.Time.Sub(o.firstTime)) / float64(flagSpeed))
+		if wait := time.Until(o.firstWall.Add(offset)); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	if err := o.out.write(r); err != nil {
+		return err
+	}
+	if f, ok := o.out.(flusher); ok {
+		if err := f.flush(); err != nil {
+			return err
+		}
+	}
+	return o.flush()
+}
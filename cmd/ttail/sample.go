@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	flagSample string
+	flagSeed   int64
+)
+
+// sampleFilter return filter keeping every Nth line for "1/N" spec
+// or lines with probability p for "p" spec, nil if sampling is off
+func sampleFilter(spec string, seed int64) (filter, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(spec, "1/") {
+		n, err := strconv.Atoi(spec[2:])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("bad sample: %s", spec)
+		}
+		count := 0
+		return func(r *record) bool {
+			count++
+			return (count-1)%n == 0
+		}, nil
+	}
+	p, err := strconv.ParseFloat(spec, 64)
+	if err != nil || p <= 0 || p > 1 {
+		return nil, fmt.Errorf("bad sample, want probability in (0, 1] or 1/N: %s", spec)
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	return func(r *record) bool {
+		return rnd.Float64() < p
+	}, nil
+}
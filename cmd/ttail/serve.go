@@ -0,0 +1,262 @@
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sakateka/ttail"
+	"go.uber.org/zap"
+)
+
+// serveChunkSize is a size of the first chunk of order=newest response
+const serveChunkSize = 64 << 10
+
+var (
+	flagListen    string
+	flagServeRoot string
+	flagDrain     time.Duration
+	flagExitIdle  time.Duration
+)
+
+var serveCommand = &command{
+	name: "serve",
+	help: "serve time windows of logs over http: GET /logs?path=&since=&until=&type=&order=, GET /metrics",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&flagListen, "listen", "127.0.0.1:8080", "address to listen")
+		fs.StringVar(&flagServeRoot, "root", "/var/log", "only files under this directory are served")
+		fs.DurationVar(&flagDrain, "drain", 30*time.Second, "time to finish active requests on SIGTERM")
+		fs.DurationVar(&flagExitIdle, "exit-idle", 0, "exit after no requests for duration, e.g. when started by systemd socket (default never)")
+		fs.IntVar(&flagMaxSearches, "max-searches", 0, "max concurrent searches, others get 503 (default unlimited)")
+		fs.Int64Var(&flagMaxBytes, "max-bytes", 0, "max bytes of response body before compression, longer responses are cut (default unlimited)")
+		fs.Float64Var(&flagRate, "rate", 0, "max requests per second of client address, others get 429 (default unlimited)")
+		fs.IntVar(&flagBurst, "burst", 5, "requests of client allowed at once over -rate")
+	},
+	run: runServe,
+}
+
+// parseTimeArg parse RFC3339 time or duration before now
+func parseTimeArg(arg string) (time.Time, error) {
+	if d, err := time.ParseDuration(arg); err == nil {
+		return now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, arg)
+}
+
+// resolvePath return real path of file under serve root, symlinks are
+// resolved before the check so links out of root are not served
+func resolvePath(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("path is required")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(flagServeRoot, path)
+	}
+	root, err := filepath.EvalSymlinks(flagServeRoot)
+	if err != nil {
+		return "", err
+	}
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return "", err
+	}
+	prefix := root
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	if path != root && !strings.HasPrefix(path, prefix) {
+		return "", errors.New("path is out of served root")
+	}
+	return path, nil
+}
+
+func serveLogs(w http.ResponseWriter, r *http.Request) {
+	status := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	w = status
+	defer func() {
+		if status.status < http.StatusBadRequest {
+			metricQueries.add("ok", 1)
+		} else {
+			metricQueries.add("error", 1)
+		}
+	}()
+
+	query := r.URL.Query()
+	path, err := resolvePath(query.Get("path"))
+	if os.IsNotExist(err) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := []ttail.TimeFileOptions{ttail.WithDuration(10 * time.Second)}
+	if since := query.Get("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			opts = append(opts, ttail.WithDuration(d))
+		} else if tm, err := time.Parse(time.RFC3339, since); err == nil {
+			opts = append(opts, ttail.WithSince(tm))
+		} else {
+			http.Error(w, "bad since: "+since, http.StatusBadRequest)
+			return
+		}
+	}
+	newest := false
+	switch order := query.Get("order"); order {
+	case "", "oldest":
+	case "newest":
+		newest = true
+	default:
+		http.Error(w, "bad order, want oldest or newest: "+order, http.StatusBadRequest)
+		return
+	}
+	if arg := query.Get("until"); arg != "" {
+		until, err := parseTimeArg(arg)
+		if err != nil {
+			http.Error(w, "bad until: "+arg, http.StatusBadRequest)
+			return
+		}
+		opts = append(opts, ttail.WithUntil(until))
+	}
+	logType := query.Get("type")
+	if logType != "" && logType != autoLogType {
+		logOpts, err := ttail.OptionsFromConfig(logType)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts = append(opts, logOpts...)
+	}
+
+	log.Debug("[serve]: query", zap.String("path", path), zap.String("query", r.URL.RawQuery))
+	src, tfile, err := openTimeSource(path, logType, opts)
+	if err == io.EOF {
+		src.Close()
+		w.WriteHeader(http.StatusOK)
+		return
+	} else if os.IsNotExist(err) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err == errBinaryFile {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	} else if err != nil {
+		log.Error("[serve]: open", zap.String("path", path), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer src.Close()
+	logType = fileLogType(path, logType)
+	redact, err := redactFilter(logType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	var out io.Writer = w
+	var zw *gzip.Writer
+	if query.Get("gzip") != "" || strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		zw = gzip.NewWriter(w)
+		defer zw.Close()
+		out = zw
+	}
+	if flagMaxBytes > 0 {
+		// limit lines before compression, so compressed response is complete
+		out = &limitWriter{w: out, n: flagMaxBytes}
+	}
+	flush := func() {
+		if zw != nil {
+			zw.Flush()
+		}
+		if f, ok := status.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	lw, err := newLineWriter(out, path, tfile, "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	lw.logType = logType
+	if redact != nil {
+		lw.filters = append(lw.filters, redact)
+	}
+	if newest {
+		err = copyNewestFirst(tfile, lw, flush)
+	} else {
+		_, err = tfile.CopyTo(lw)
+	}
+	if errors.Is(err, errResponseLimit) {
+		log.Warn("[serve]: response is cut", zap.String("path", path), zap.Int64("maxBytes", flagMaxBytes))
+		return
+	} else if err != nil {
+		log.Error("[serve]: copy", zap.String("path", path), zap.Error(err))
+		return
+	}
+	if err := closeWindow(lw); err != nil {
+		log.Error("[serve]: copy", zap.String("path", path), zap.Error(err))
+	}
+}
+
+// copyNewestFirst write time window by chunks from the newest lines to the
+// oldest ones, every chunk is sent at once so client shows the end of large
+// window while older lines are read, lines of chunk are in time order
+func copyNewestFirst(tfile *ttail.TFile, lw *lineWriter, flush func()) error {
+	chunks := tfile.NewestFirst(serveChunkSize)
+	for chunks.Next() {
+		if _, err := lw.Write(chunks.Chunk().Data); err != nil {
+			return err
+		}
+		if err := closeWindow(lw); err != nil {
+			return err
+		}
+		flush()
+	}
+	return chunks.Err()
+}
+
+// statusWriter remember response status for metrics
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// reloadConfigOnHUP read config of log types again on SIGHUP,
+// it is read once and shared by requests
+func reloadConfigOnHUP() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := ttail.PreloadConfig(); err != nil {
+				log.Error("[serve]: reload config", zap.Error(err))
+				continue
+			}
+			log.Debug("[serve]: config reloaded")
+		}
+	}()
+}
+
+func runServe(fs *flag.FlagSet) error {
+	mux := http.NewServeMux()
+	reloadConfigOnHUP()
+	limits := newLimiter(flagMaxSearches, flagRate, flagBurst)
+	mux.HandleFunc("/logs", limits.wrap(serveLogs))
+	mux.HandleFunc("/metrics", serveMetrics)
+	return serveGraceful(flagListen, mux, flagDrain, flagExitIdle)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var flagEvery time.Duration
+
+var shipCommand = &command{
+	name: "ship",
+	args: "file[:duration] [file[:duration] ...]",
+	help: "periodically push lines appended since the last run to -loki, -es or stdout",
+	flags: func(fs *flag.FlagSet) {
+		windowFlags(fs)
+		untilFlag(fs)
+		outputFlags(fs)
+		cursorFlags(fs)
+		fs.DurationVar(&flagEvery, "every", time.Minute, "interval between shipping of new lines")
+	},
+	run: runShip,
+}
+
+func runShip(fs *flag.FlagSet) error {
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if flagCursorFile == "" {
+		return errors.New("ship requires -cursor-file to remember shipped lines")
+	}
+	if flagEvery <= 0 {
+		return errors.New("-every must be positive")
+	}
+	cursors, err := loadCursors(flagCursorFile)
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(flagEvery)
+	defer ticker.Stop()
+	for {
+		// the first run ships the time window, next ones continue from cursors
+		for _, arg := range fs.Args() {
+			if err := copyWindow(arg, cursors); err != nil {
+				return err
+			}
+		}
+		if err := cursors.save(); err != nil {
+			return err
+		}
+		select {
+		case sig := <-stop:
+			log.Debug("[ship]: stop", zap.String("signal", sig.String()))
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
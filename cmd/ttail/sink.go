@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	flagLoki       string
+	flagLokiLabels string
+	flagES         string
+	flagESIndex    string
+)
+
+// sinkBatchSize is a number of records pushed by one request
+const sinkBatchSize = 1000
+
+// flusher is an output which buffers records
+type flusher interface {
+	flush() error
+}
+
+// newSinkOutput return output pushing records to -loki or -es,
+// nil if no sink is set
+func newSinkOutput() (output, error) {
+	switch {
+	case flagLoki != "" && flagES != "":
+		return nil, fmt.Errorf("-loki and -es can't be used together")
+	case flagLoki != "":
+		labels := map[string]string{"job": "ttail"}
+		if flagLogType != "" && flagLogType != autoLogType {
+			labels["type"] = flagLogType
+		}
+		for _, kv := range strings.Split(flagLokiLabels, ",") {
+			if kv == "" {
+				continue
+			}
+			idx := strings.IndexByte(kv, '=')
+			if idx <= 0 {
+				return nil, fmt.Errorf("bad loki label: %q", kv)
+			}
+			labels[kv[:idx]] = kv[idx+1:]
+		}
+		return &lokiOutput{url: flagLoki, labels: labels}, nil
+	case flagES != "":
+		return &esOutput{url: strings.TrimSuffix(flagES, "/") + "/_bulk", index: flagESIndex}, nil
+	}
+	return nil, nil
+}
+
+// recordTime return time of record, lines before the first parsed time get now
+func recordTime(r *record) time.Time {
+	if r.Time.IsZero() {
+		return time.Now()
+	}
+	return r.Time
+}
+
+// postBatch send body to url, check response status and return response body
+func postBatch(url, contentType string, body []byte) ([]byte, error) {
+	resp, err := http.Post(url, contentType, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s: %s: %s", url, resp.Status, bytes.TrimSpace(msg))
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// lokiOutput push records to grafana loki push api,
+// every file is a separate stream labeled by filename
+type lokiOutput struct {
+	url     string
+	labels  map[string]string
+	streams map[string][][2]string
+	count   int
+}
+
+func (o *lokiOutput) write(r *record) error {
+	if o.streams == nil {
+		o.streams = map[string][][2]string{}
+	}
+	ts := strconv.FormatInt(recordTime(r).UnixNano(), 10)
+	o.streams[r.File] = append(o.streams[r.File], [2]string{ts, r.Line})
+	o.count++
+	if o.count >= sinkBatchSize {
+		return o.flush()
+	}
+	return nil
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (o *lokiOutput) flush() error {
+	if o.count == 0 {
+		return nil
+	}
+	var push struct {
+		Streams []lokiStream `json:"streams"`
+	}
+	for file, values := range o.streams {
+		labels := map[string]string{"filename": file}
+		for k, v := range o.labels {
+			labels[k] = v
+		}
+		push.Streams = append(push.Streams, lokiStream{Stream: labels, Values: values})
+	}
+	o.streams, o.count = nil, 0
+	body, err := json.Marshal(push)
+	if err != nil {
+		return err
+	}
+	_, err = postBatch(o.url, "application/json", body)
+	return err
+}
+
+// esOutput push records to elasticsearch bulk api
+type esOutput struct {
+	url   string
+	index string
+	body  bytes.Buffer
+	count int
+}
+
+type esDocument struct {
+	Timestamp time.Time `json:"@timestamp"`
+	Message   string    `json:"message"`
+	File      string    `json:"file,omitempty"`
+	Type      string    `json:"type,omitempty"`
+}
+
+// esBulk append bulk api index action and document of record to body
+func esBulk(body *bytes.Buffer, index string, r *record) error {
+	action, err := json.Marshal(map[string]map[string]string{"index": {"_index": index}})
+	if err != nil {
+		return err
+	}
+	doc, err := json.Marshal(esDocument{
+		Timestamp: recordTime(r),
+		Message:   r.Line,
+		File:      r.File,
+		Type:      fileLogType(r.File, flagLogType),
+	})
+	if err != nil {
+		return err
+	}
+	body.Write(action)
+	body.WriteByte('\n')
+	body.Write(doc)
+	body.WriteByte('\n')
+	return nil
+}
+
+// esBulkOutput write records as body of elasticsearch bulk api request,
+// e.g. to post it later with curl --data-binary
+type esBulkOutput struct {
+	w     io.Writer
+	index string
+	body  bytes.Buffer
+}
+
+func (o *esBulkOutput) write(r *record) error {
+	o.body.Reset()
+	if err := esBulk(&o.body, o.index, r); err != nil {
+		return err
+	}
+	_, err := o.w.Write(o.body.Bytes())
+	return err
+}
+
+func (o *esOutput) write(r *record) error {
+	if err := esBulk(&o.body, o.index, r); err != nil {
+		return err
+	}
+	o.count++
+	if o.count >= sinkBatchSize {
+		return o.flush()
+	}
+	return nil
+}
+
+func (o *esOutput) flush() error {
+	if o.count == 0 {
+		return nil
+	}
+	body := o.body.Bytes()
+	o.body.Reset()
+	o.count = 0
+
+	resp, err := postBatch(o.url, "application/x-ndjson", body)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Error json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return err
+	}
+	if result.Errors {
+		for _, item := range result.Items {
+			for _, res := range item {
+				if len(res.Error) > 0 {
+					return fmt.Errorf("%s: bulk error: %s", o.url, res.Error)
+				}
+			}
+		}
+		return fmt.Errorf("%s: bulk request has errors", o.url)
+	}
+	return nil
+}
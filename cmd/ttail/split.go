@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	flagSplit       time.Duration
+	flagSplitLayout string
+)
+
+// splitCreated remember split files created by this run, they are
+// truncated when opened first and appended after
+var splitCreated = map[string]bool{}
+
+// splitOutput write lines into files of time buckets named after input file,
+// e.g. app-1030.log, app-1040.log for app.log split by 10m
+type splitOutput struct {
+	base, ext string
+	bucket    time.Time
+	file      *os.File
+	buf       *bufio.Writer
+	// pending lines without time before the first bucket
+	pending []string
+}
+
+func newSplitOutput(fname string) *splitOutput {
+	base := strings.TrimSuffix(filepath.Base(fname), ".gz")
+	if fname == "" || fname == "-" {
+		base = "ttail.log"
+	}
+	ext := filepath.Ext(base)
+	return &splitOutput{base: strings.TrimSuffix(base, ext), ext: ext}
+}
+
+// name of file of bucket
+func (o *splitOutput) name(bucket time.Time) string {
+	return o.base + "-" + bucket.Format(flagSplitLayout) + o.ext
+}
+
+// open file of bucket
+func (o *splitOutput) open(bucket time.Time) error {
+	if err := o.close(); err != nil {
+		return err
+	}
+	name := o.name(bucket)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if !splitCreated[name] {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(name, flags, 0644)
+	if err != nil {
+		return err
+	}
+	splitCreated[name] = true
+	o.bucket, o.file = bucket, f
+	if o.buf == nil {
+		o.buf = bufio.NewWriter(f)
+	} else {
+		o.buf.Reset(f)
+	}
+	return nil
+}
+
+func (o *splitOutput) write(r *record) error {
+	// lines without time belong to the bucket of the previous line
+	bucket := o.bucket
+	if !r.Time.IsZero() {
+		bucket = r.Time.Truncate(flagSplit)
+	}
+	if bucket.IsZero() {
+		o.pending = append(o.pending, r.Line)
+		return nil
+	}
+	if o.file == nil || !bucket.Equal(o.bucket) {
+		if err := o.open(bucket); err != nil {
+			return err
+		}
+	}
+	for _, line := range o.pending {
+		if err := o.writeLine(line); err != nil {
+			return err
+		}
+	}
+	o.pending = nil
+	return o.writeLine(r.Line)
+}
+
+func (o *splitOutput) writeLine(line string) error {
+	if _, err := io.WriteString(o.buf, line); err != nil {
+		return err
+	}
+	return o.buf.WriteByte('\n')
+}
+
+// flush close file of the current bucket, it is opened again
+// for the next lines of the same bucket
+func (o *splitOutput) flush() error {
+	return o.close()
+}
+
+func (o *splitOutput) close() error {
+	if o.file == nil {
+		return nil
+	}
+	err := o.buf.Flush()
+	if cerr := o.file.Close(); err == nil {
+		err = cerr
+	}
+	o.file = nil
+	return err
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/sakateka/ttail"
+)
+
+var flagTraceID string
+
+// traceFilter keep lines of trace, nil if trace filter is off
+func traceFilter(tfile *ttail.TFile, traceID string) filter {
+	if traceID == "" {
+		return nil
+	}
+	traceID = strings.ToLower(traceID)
+	return func(r *record) bool {
+		id, _ := tfile.ParseTrace([]byte(r.Line))
+		return id == traceID
+	}
+}
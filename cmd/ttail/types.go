@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/sakateka/ttail"
+)
+
+var typesCommand = &command{
+	name: "types",
+	help: "list log types from config and builtin ones",
+	run:  runTypes,
+}
+
+func runTypes(fs *flag.FlagSet) error {
+	conf, err := ttail.DefaultConfig()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(conf))
+	for name := range conf {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tLAYOUT\tREGEXP")
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\t%q\n", name, conf[name].TimeLayout, conf[name].TimeReStr)
+	}
+	return w.Flush()
+}
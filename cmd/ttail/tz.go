@@ -0,0 +1,22 @@
+package main
+
+import (
+	"time"
+
+	"github.com/sakateka/ttail"
+)
+
+var flagTZ string
+
+// tzFilter rewrite time of lines into loc
+func tzFilter(tfile *ttail.TFile, loc *time.Location) filter {
+	return func(r *record) bool {
+		if line, err := tfile.ConvertTime([]byte(r.Line), loc); err == nil {
+			r.Line = string(line)
+		}
+		if !r.Time.IsZero() {
+			r.Time = r.Time.In(loc)
+		}
+		return true
+	}
+}
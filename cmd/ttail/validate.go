@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/sakateka/ttail"
+)
+
+var validateCommand = &command{
+	name: "validate",
+	args: "[config]",
+	help: "check log types in config file",
+	run:  runValidate,
+}
+
+func runValidate(fs *flag.FlagSet) error {
+	path := ttail.DefaultConfigFile
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	conf, err := ttail.LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(conf))
+	for name := range conf {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failed := 0
+	for _, name := range names {
+		if err := conf[name].Validate(); err != nil {
+			failed++
+			fmt.Fprintf(os.Stdout, "%s: %s\n", name, err)
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "%s: ok\n", name)
+	}
+	if failed > 0 {
+		return errors.New(path + ": invalid log types found")
+	}
+	return nil
+}
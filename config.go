@@ -0,0 +1,125 @@
+package ttail
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// configCache is a snapshot of default config and options of its types
+// shared by all files, it is replaced as a whole and never modified
+var configCache struct {
+	sync.Mutex
+	path    string
+	conf    Config
+	options map[string][]TimeFileOptions
+}
+
+// compiled regexps of types by source, detection of log type compiles
+// regexps of all types for every file
+var compiled sync.Map
+
+// compileCached return compiled regexp re shared by all callers
+func compileCached(re string) *regexp.Regexp {
+	if v, ok := compiled.Load(re); ok {
+		return v.(*regexp.Regexp)
+	}
+	v, _ := compiled.LoadOrStore(re, regexp.MustCompile(re))
+	return v.(*regexp.Regexp)
+}
+
+// loadDefaultConfig read types of DefaultConfigFile and builtin types,
+// only builtin types are returned when the file does not exist
+func loadDefaultConfig() (Config, error) {
+	conf := Config{}
+	if _, err := os.Stat(DefaultConfigFile); !os.IsNotExist(err) {
+		if conf, err = LoadConfig(DefaultConfigFile); err != nil {
+			return nil, err
+		}
+	}
+	if conf == nil {
+		// empty config file
+		conf = Config{}
+	}
+	for name, aType := range BuiltinTypes {
+		if _, ok := conf[name]; !ok {
+			conf[name] = aType
+		}
+	}
+	return conf, nil
+}
+
+// snapshot return cached config of DefaultConfigFile, loading it if needed,
+// configCache must be locked
+func snapshot() (Config, error) {
+	if configCache.conf != nil && configCache.path == DefaultConfigFile {
+		return configCache.conf, nil
+	}
+	conf, err := loadDefaultConfig()
+	if err != nil {
+		return nil, err
+	}
+	configCache.path, configCache.conf = DefaultConfigFile, conf
+	configCache.options = map[string][]TimeFileOptions{}
+	return conf, nil
+}
+
+// DefaultConfig return types of DefaultConfigFile and builtin types,
+// config is read once and shared, so it must not be modified
+func DefaultConfig() (Config, error) {
+	configCache.Lock()
+	defer configCache.Unlock()
+	return snapshot()
+}
+
+// PreloadConfig read DefaultConfigFile again and compile options of all
+// valid types, so files opened later share them, embedders may call it
+// on start and on config change
+func PreloadConfig() error {
+	conf, err := loadDefaultConfig()
+	if err != nil {
+		return err
+	}
+	options := map[string][]TimeFileOptions{}
+	for name, aType := range conf {
+		if aType.Validate() != nil {
+			continue
+		}
+		if opts, err := aType.Options(); err == nil {
+			options[name] = opts
+		}
+	}
+	configCache.Lock()
+	configCache.path, configCache.conf, configCache.options = DefaultConfigFile, conf, options
+	configCache.Unlock()
+	releaseWasmParsers(conf)
+	return nil
+}
+
+// OptionsFromConfig return options of log type of default config,
+// they are built once and shared
+func OptionsFromConfig(logType string) ([]TimeFileOptions, error) {
+	configCache.Lock()
+	defer configCache.Unlock()
+	conf, err := snapshot()
+	if err != nil {
+		return nil, err
+	}
+	opts, ok := configCache.options[logType]
+	if !ok {
+		aType, ok := conf[logType]
+		if !ok {
+			return nil, errors.New("Failed to find options for log type: " + logType)
+		}
+		if err := aType.Validate(); err != nil {
+			return nil, errors.New("Invalid options for log type " + logType + ": " + err.Error())
+		}
+		if opts, err = aType.Options(); err != nil {
+			return nil, errors.New("Invalid options for log type " + logType + ": " + err.Error())
+		}
+		configCache.options[logType] = opts
+	}
+	// append of caller must not write into shared array
+	return opts[:len(opts):len(opts)], nil
+}
@@ -0,0 +1,127 @@
+package ttail
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	// detectLines is a number of lines used to detect log type
+	detectLines = 100
+	// detectMaxLine is a length of line taken into account
+	detectMaxLine = 64 << 10
+)
+
+// ErrNoLogType returned when no log type of config matches lines
+var ErrNoLogType = errors.New("log type is not detected")
+
+// TypeMatch is a share of lines with time parsed by log type
+type TypeMatch struct {
+	Name string
+	Rate float64
+	// layout breaks ties of rates
+	layout string
+}
+
+// DetectLogType find type of config which parses time in the most of first
+// lines of r, confidence is a share of lines with parsed time,
+// ties are broken by the longer time layout and then by type name
+func DetectLogType(r io.Reader, conf Config) (name string, confidence float64, err error) {
+	var lines [][]byte
+	br := bufio.NewReaderSize(r, detectMaxLine)
+	for len(lines) < detectLines {
+		line, rerr := br.ReadSlice('\n')
+		if rerr == bufio.ErrBufferFull {
+			// skip rest of too long line
+			for rerr == bufio.ErrBufferFull {
+				_, rerr = br.ReadSlice('\n')
+			}
+		} else if len(line) > 0 {
+			lines = append(lines, append([]byte(nil), line...))
+		}
+		if rerr == io.EOF {
+			break
+		} else if rerr != nil && rerr != bufio.ErrBufferFull {
+			return "", 0, rerr
+		}
+	}
+	matches := RankLogTypes(lines, conf)
+	if len(matches) == 0 || matches[0].Rate == 0 {
+		return "", 0, ErrNoLogType
+	}
+	return matches[0].Name, matches[0].Rate, nil
+}
+
+// RankLogTypes return valid types of config ordered by share of lines
+// with parsed time, ties are broken by the longer time layout and then by type name
+func RankLogTypes(lines [][]byte, conf Config) []TypeMatch {
+	if len(lines) == 0 {
+		return nil
+	}
+	var matches []TypeMatch
+	for n, aType := range conf {
+		if aType.Validate() != nil {
+			continue
+		}
+		rate, layout := aType.matchRate(lines)
+		matches = append(matches, TypeMatch{Name: n, Rate: rate, layout: layout})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Rate != b.Rate {
+			return a.Rate > b.Rate
+		}
+		if len(a.layout) != len(b.layout) {
+			return len(a.layout) > len(b.layout)
+		}
+		return a.Name < b.Name
+	})
+	return matches
+}
+
+// matchRate return share of lines with time parsed by valid type and its time layout
+func (t Type) matchRate(lines [][]byte) (float64, string) {
+	re, layout := defaultOptions.timeRe, defaultOptions.timeLayout
+	if t.TimeReStr != "" {
+		re = compileCached(t.TimeReStr)
+	}
+	if t.TimeLayout != "" {
+		layout = t.TimeLayout
+	}
+	extractors := []extractor{{re: re, layout: layout}}
+	for _, e := range t.Extractors {
+		extractors = append(extractors, extractor{re: compileCached(e.TimeReStr), layout: e.TimeLayout})
+	}
+	var parse TimeParser
+	if t.Wasm != "" {
+		// module is cached by validation
+		var err error
+		if parse, err = loadWasmTimeParser(t.Wasm); err != nil {
+			return 0, ""
+		}
+		layout = ""
+	}
+	matched := 0
+	for _, line := range lines {
+		if parse != nil {
+			if _, err := parse(line); err == nil {
+				matched++
+			}
+			continue
+		}
+		for _, e := range extractors {
+			subm := e.re.FindSubmatch(line)
+			if subm == nil {
+				continue
+			}
+			if _, err := time.Parse(e.layout, string(subm[1])); err == nil {
+				matched++
+				break
+			}
+		}
+	}
+	return float64(matched) / float64(len(lines)), layout
+}
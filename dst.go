@@ -0,0 +1,72 @@
+package ttail
+
+import "time"
+
+// DSTPolicy choose time of local wall clock which is ambiguous (repeated
+// when clocks are set back) or nonexistent (skipped when clocks are set forward)
+type DSTPolicy string
+
+const (
+	// DSTDefault keep time chosen by time.ParseInLocation
+	DSTDefault DSTPolicy = ""
+	// DSTEarliest choose the earliest of possible times
+	DSTEarliest DSTPolicy = "earliest"
+	// DSTLatest choose the latest of possible times
+	DSTLatest DSTPolicy = "latest"
+	// DSTUTC read ambiguous and nonexistent wall clock as UTC
+	DSTUTC DSTPolicy = "utc"
+)
+
+// valid report whether policy is known
+func (p DSTPolicy) valid() bool {
+	switch p {
+	case DSTDefault, DSTEarliest, DSTLatest, DSTUTC:
+		return true
+	}
+	return false
+}
+
+// layoutHasZone report whether layout contains zone offset or abbreviation,
+// times of such layouts are not local wall clock
+func layoutHasZone(layout string) bool {
+	a := time.Date(2001, 2, 3, 4, 5, 6, 0, time.FixedZone("AAA", 3600))
+	b := time.Date(2001, 2, 3, 4, 5, 6, 0, time.FixedZone("BBB", 7200))
+	return a.Format(layout) != b.Format(layout)
+}
+
+// resolveWallClock return time of wall clock w (fields of w in UTC)
+// in loc by policy
+func resolveWallClock(w time.Time, loc *time.Location, policy DSTPolicy) time.Time {
+	// offsets of loc before and after possible transition
+	_, before := w.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := w.Add(24 * time.Hour).In(loc).Zone()
+	var candidates []time.Time
+	for _, offset := range []int{before, after} {
+		c := w.Add(-time.Duration(offset) * time.Second)
+		if sameWallClock(c.In(loc), w) && (len(candidates) == 0 || !candidates[0].Equal(c)) {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0].In(loc)
+	}
+	if policy == DSTUTC {
+		return w
+	}
+	earliest := w.Add(-time.Duration(before) * time.Second)
+	latest := w.Add(-time.Duration(after) * time.Second)
+	if latest.Before(earliest) {
+		earliest, latest = latest, earliest
+	}
+	if policy == DSTLatest {
+		return latest.In(loc)
+	}
+	return earliest.In(loc)
+}
+
+func sameWallClock(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd &&
+		a.Hour() == b.Hour() && a.Minute() == b.Minute() && a.Second() == b.Second()
+}
@@ -0,0 +1,66 @@
+package ttail
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// rangeReader is a section of file closed with it
+type rangeReader struct {
+	*io.SectionReader
+	file *os.File
+}
+
+func (r *rangeReader) Close() error {
+	return r.file.Close()
+}
+
+// ExtractRange open log file and return reader of its lines with time
+// from from to to inclusive, logType is a type of config or empty for
+// default options, both bounds are found by FindPosition
+//
+//	r, err := ttail.ExtractRange("/var/log/app.log", from, to, "java")
+//	if err != nil {
+//		return err
+//	}
+//	defer r.Close()
+//	_, err = io.Copy(os.Stdout, r)
+func ExtractRange(path string, from, to time.Time, logType string) (io.ReadCloser, error) {
+	if to.Before(from) {
+		return nil, errors.New("ExtractRange: to is before from")
+	}
+	var opts []TimeFileOptions
+	if logType != "" {
+		var err error
+		if opts, err = OptionsFromConfig(logType); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	tfile := NewTimeFile(file, append(opts, WithSince(from), WithUntil(to))...)
+	if tfile.opts.descending {
+		file.Close()
+		return nil, errors.New("ExtractRange: descending log is not supported")
+	}
+	start, end := int64(0), int64(0)
+	switch err = tfile.FindPosition(); {
+	case err == io.EOF:
+		// all lines are before from
+		start, end = tfile.Size(), tfile.Size()
+	case err != nil:
+		file.Close()
+		return nil, errors.Wrap(err, "ExtractRange")
+	case tfile.Stats().NoTime:
+		file.Close()
+		return nil, errors.Wrap(ErrNoTime, "ExtractRange")
+	default:
+		start, end = tfile.Offset(), tfile.bound()
+	}
+	return &rangeReader{SectionReader: io.NewSectionReader(file, start, end-start), file: file}, nil
+}
@@ -0,0 +1,159 @@
+package ttail
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// FieldParser extract fields from line, nil means line has no fields
+type FieldParser func(line string) map[string]string
+
+// FieldParser of type, nil if type does not define fields
+func (t Type) FieldParser() (FieldParser, error) {
+	if t.FieldsReStr != "" {
+		re, err := regexp.Compile(t.FieldsReStr)
+		if err != nil {
+			return nil, err
+		}
+		return regexpFields(re), nil
+	}
+	switch t.Fields {
+	case "":
+		return nil, nil
+	case "logfmt":
+		return LogfmtFields, nil
+	case "tskv":
+		return TSKVFields, nil
+	case "json":
+		return JSONFields, nil
+	}
+	return nil, errors.New("unknown Fields format: " + t.Fields)
+}
+
+// regexpFields extract named groups of re
+func regexpFields(re *regexp.Regexp) FieldParser {
+	names := re.SubexpNames()
+	return func(line string) map[string]string {
+		m := re.FindStringSubmatch(line)
+		if m == nil {
+			return nil
+		}
+		fields := make(map[string]string, len(names))
+		for i, name := range names {
+			if name != "" && i < len(m) {
+				fields[name] = m[i]
+			}
+		}
+		return fields
+	}
+}
+
+// TSKVFields parse tab separated key=value pairs
+func TSKVFields(line string) map[string]string {
+	fields := map[string]string{}
+	for _, kv := range strings.Split(line, "\t") {
+		if idx := strings.IndexByte(kv, '='); idx > 0 {
+			fields[kv[:idx]] = kv[idx+1:]
+		}
+	}
+	return fields
+}
+
+// LogfmtFields parse space separated key=value pairs, values may be double quoted
+func LogfmtFields(line string) map[string]string {
+	fields := map[string]string{}
+	for len(line) > 0 {
+		line = strings.TrimLeft(line, " \t")
+		end := strings.IndexAny(line, "= \t")
+		if end < 0 {
+			end = len(line)
+		}
+		key := line[:end]
+		line = line[end:]
+		if !strings.HasPrefix(line, "=") {
+			if key != "" {
+				fields[key] = ""
+			}
+			continue
+		}
+		line = line[1:]
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			value, line = unquoteLogfmt(line)
+		} else {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			value, line = line[:end], line[end:]
+		}
+		if key != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// unquoteLogfmt return value of quoted string at the start of s and the rest of s
+func unquoteLogfmt(s string) (string, string) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(s[i])
+				}
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
+
+// JSONFields parse top level keys of json object, nested values are kept as json
+func JSONFields(line string) map[string]string {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &obj); err != nil {
+		return nil
+	}
+	fields := make(map[string]string, len(obj))
+	for key, raw := range obj {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			fields[key] = s
+		} else {
+			fields[key] = strings.TrimSpace(string(raw))
+		}
+	}
+	return fields
+}
+
+// validateFields check fields options of type
+func (t Type) validateFields() error {
+	if t.FieldsReStr != "" {
+		re, err := regexp.Compile(t.FieldsReStr)
+		if err != nil {
+			return err
+		}
+		for _, name := range re.SubexpNames() {
+			if name != "" {
+				return nil
+			}
+		}
+		return errors.New("FieldsReStr must contain named groups")
+	}
+	_, err := t.FieldParser()
+	return err
+}
@@ -0,0 +1,114 @@
+package ttail
+
+import (
+	"bytes"
+	"io"
+)
+
+// filtered report whether lines of time window are dropped
+// by parse failure policy or minimal level
+func (o options) filtered() bool {
+	return o.parseFailure.drops() || o.minLevel != ""
+}
+
+// keep report whether line of time window is emitted, lines without time
+// are handled by parse failure policy and by default follow the previous line,
+// lines with time are checked against minimal level
+func (t *TFile) keep(hasTime bool, level string) (bool, error) {
+	if !hasTime {
+		switch t.opts.parseFailure {
+		case ParseFailureSkip:
+			return false, nil
+		case ParseFailureStop:
+			return false, ErrNoTime
+		}
+		return t.kept || t.opts.minLevel == "", nil
+	}
+	t.kept = t.opts.minLevel == "" || LevelSeverity(level) >= LevelSeverity(t.opts.minLevel)
+	return t.kept, nil
+}
+
+// keepLine report whether raw line of time window is emitted
+func (t *TFile) keepLine(line []byte) (bool, error) {
+	line = bytes.TrimRight(line, "\r\n")
+	_, err := t.ParseTime(line)
+	var level string
+	if err == nil && t.opts.minLevel != "" {
+		level = t.ParseLevel(line)
+	}
+	return t.keep(err == nil, level)
+}
+
+// FilterLines return complete lines emitted by parse failure policy and
+// minimal level, e.g. lines appended to followed file
+func (t *TFile) FilterLines(lines []byte) ([]byte, error) {
+	if !t.opts.filtered() {
+		return lines, nil
+	}
+	var b bytes.Buffer
+	err := (&filterWriter{t: t, w: &b}).writeLines(lines)
+	return b.Bytes(), err
+}
+
+// filterWriter pass emitted lines to w and drop others,
+// written bytes are reported as consumed even if lines are dropped
+type filterWriter struct {
+	t       *TFile
+	w       io.Writer
+	pending []byte
+}
+
+func (f *filterWriter) Write(b []byte) (int, error) {
+	f.pending = append(f.pending, b...)
+	idx := bytes.LastIndexByte(f.pending, '\n')
+	if idx < 0 {
+		return len(b), nil
+	}
+	lines := f.pending[:idx+1]
+	if err := f.writeLines(lines); err != nil {
+		return 0, err
+	}
+	f.pending = append(f.pending[:0], f.pending[idx+1:]...)
+	return len(b), nil
+}
+
+// writeLines write complete lines, runs of emitted lines are written at once
+func (f *filterWriter) writeLines(lines []byte) error {
+	start := 0
+	for pos := 0; pos < len(lines); {
+		end := bytes.IndexByte(lines[pos:], '\n')
+		if end < 0 {
+			end = len(lines)
+		} else {
+			end += pos + 1
+		}
+		keep, err := f.t.keepLine(lines[pos:end])
+		if !keep {
+			if start < pos {
+				if _, err := f.w.Write(lines[start:pos]); err != nil {
+					return err
+				}
+			}
+			if err != nil {
+				return err
+			}
+			start = end
+		}
+		pos = end
+	}
+	if start < len(lines) {
+		_, err := f.w.Write(lines[start:])
+		return err
+	}
+	return nil
+}
+
+// flush write the last line without line end
+func (f *filterWriter) flush() error {
+	if len(f.pending) == 0 {
+		return nil
+	}
+	err := f.writeLines(f.pending)
+	f.pending = f.pending[:0]
+	return err
+}
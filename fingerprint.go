@@ -0,0 +1,61 @@
+package ttail
+
+import (
+	"bytes"
+	"hash/fnv"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// fingerprintMaxSize limit hashed head of file with long first line
+const fingerprintMaxSize = 4 << 10
+
+// Fingerprint identify content of file by hash of its head up to the end
+// of the first line, appended file keeps fingerprint while file rewritten
+// in place or new file with reused inode gets another one
+type Fingerprint struct {
+	// Size of hashed head
+	Size int64  `json:"size"`
+	Hash uint64 `json:"hash"`
+	// Partial is set when first line is not complete yet,
+	// fingerprint should be taken again when file grows
+	Partial bool `json:"partial,omitempty"`
+}
+
+// NewFingerprint take fingerprint of head of r
+func NewFingerprint(r io.ReaderAt) (Fingerprint, error) {
+	buf := make([]byte, fingerprintMaxSize)
+	n, err := r.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return Fingerprint{}, errors.Wrap(err, "NewFingerprint")
+	}
+	buf = buf[:n]
+	partial := n < fingerprintMaxSize
+	if idx := bytes.IndexByte(buf, '\n'); idx >= 0 {
+		buf, partial = buf[:idx+1], false
+	}
+	return Fingerprint{Size: int64(len(buf)), Hash: hashHead(buf), Partial: partial}, nil
+}
+
+// Match report whether r starts with head of fingerprint
+func (f Fingerprint) Match(r io.ReaderAt) (bool, error) {
+	if f.Size == 0 {
+		return true, nil
+	}
+	buf := make([]byte, f.Size)
+	n, err := r.ReadAt(buf, 0)
+	if int64(n) < f.Size {
+		if err != nil && err != io.EOF {
+			return false, errors.Wrap(err, "Match")
+		}
+		return false, nil
+	}
+	return hashHead(buf) == f.Hash, nil
+}
+
+func hashHead(p []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(p)
+	return h.Sum64()
+}
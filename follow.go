@@ -0,0 +1,79 @@
+package ttail
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Follow copy time window found by FindPosition to w and then write complete
+// lines appended to file until ctx is done, lines are filtered like by CopyTo,
+// file truncated or rewritten in place is read from the beginning, rotation
+// is not followed, window with end found before the end of file is only copied
+// and following stops at the first line after end time, if FindPosition
+// returns io.EOF call SetOffset(Size()) to follow new lines
+func (t *TFile) Follow(ctx context.Context, w io.Writer) error {
+	if t.opts.descending {
+		return errors.New("Follow: descending log is not supported")
+	}
+	if t.end >= 0 && t.end < t.size {
+		_, err := t.CopyTo(w)
+		return err
+	}
+	if t.opts.filtered() {
+		w = &filterWriter{t: t, w: w}
+	}
+	tail, err := NewTail(t.file, t.offset)
+	if err != nil {
+		return errors.Wrap(err, "Follow")
+	}
+	buf := make([]byte, 1<<16)
+	write := func(lines []byte) error {
+		done, err := t.writeFollowed(w, lines)
+		if err == nil && done {
+			err = errFollowEnd
+		}
+		return err
+	}
+	for {
+		if truncated, err := tail.Check(); err != nil {
+			return err
+		} else if truncated {
+			debug("[Follow]: %s truncated, read from the beginning", t.name)
+		}
+		if err := tail.Read(buf, write); err == errFollowEnd {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(t.opts.poll):
+		}
+	}
+}
+
+// errFollowEnd stop Follow at the first line after until time
+var errFollowEnd = errors.New("end of followed window")
+
+// writeFollowed write complete lines, done is set when line after
+// until time is found, lines before it are written
+func (t *TFile) writeFollowed(w io.Writer, lines []byte) (done bool, err error) {
+	if !t.until.IsZero() {
+		for pos := 0; pos < len(lines); {
+			end := bytes.IndexByte(lines[pos:], '\n') + pos + 1
+			if tm, err := t.ParseTime(lines[pos : end-1]); err == nil && tm.After(t.until) {
+				lines, done = lines[:pos], true
+				break
+			}
+			pos = end
+		}
+	}
+	_, err = w.Write(lines)
+	return done, err
+}
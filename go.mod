@@ -1,13 +1,20 @@
 module github.com/sakateka/ttail
 
+go 1.27.1
+
 require (
 	github.com/BurntSushi/toml v0.3.1
+	github.com/klauspost/compress v1.18.0
 	github.com/pkg/errors v0.8.1
+	github.com/tetratelabs/wazero v1.9.0
+	github.com/ulikunitz/xz v0.5.15
 	go.uber.org/zap v1.10.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/objx v0.1.0 // indirect
 	github.com/stretchr/testify v1.3.0 // indirect
 	go.uber.org/atomic v1.4.0 // indirect
 	go.uber.org/multierr v1.1.0 // indirect
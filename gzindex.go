@@ -0,0 +1,234 @@
+package ttail
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// GzipIndexSuffix is a suffix of gzip seek index file name next to gzip file
+const GzipIndexSuffix = ".ttgzi"
+
+var gzipIndexMagic = []byte("TTGZI1\n")
+
+// ErrGzipSingleMember is returned by BuildGzipIndex for gzip stream of one
+// member, it has no seek points besides the beginning
+var ErrGzipSingleMember = errors.New("single member gzip can't be read at random offset, recompress it by many members (e.g. by bgzip or pigz --independent)")
+
+// GzipMember is a position of gzip member (independently compressed part)
+type GzipMember struct {
+	Offset int64 // offset in compressed file
+	Start  int64 // offset of member data in uncompressed data
+}
+
+// GzipIndex is a list of seek points of gzip file, gzip members are seek points,
+// so files written as many members (e.g. by bgzip, pigz --independent or
+// concatenation of gzip files) can be read at random offsets without
+// decompression from the beginning, flush points inside of member and
+// zstd frames are not indexed
+type GzipIndex struct {
+	// Size of compressed file
+	Size int64
+	// USize is a size of uncompressed data
+	USize   int64
+	Members []GzipMember
+}
+
+// countingReader count bytes consumed by gzip reader, it implements
+// io.ByteReader so gzip reader does not read ahead
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// BuildGzipIndex read whole gzip stream and find its members,
+// ErrGzipSingleMember is returned if stream has only one member
+func BuildGzipIndex(r io.Reader) (*GzipIndex, error) {
+	cr := &countingReader{r: bufio.NewReader(r)}
+	zr, err := gzip.NewReader(cr)
+	if err != nil {
+		return nil, errors.Wrap(err, "BuildGzipIndex")
+	}
+	idx := &GzipIndex{}
+	for {
+		// header of member is already read by NewReader or Reset
+		idx.Members = append(idx.Members, GzipMember{Offset: idx.Size, Start: idx.USize})
+		zr.Multistream(false)
+		n, err := io.Copy(ioutil.Discard, zr)
+		if err != nil {
+			return nil, errors.Wrap(err, "BuildGzipIndex")
+		}
+		idx.USize += n
+		idx.Size = cr.n
+		if err := zr.Reset(cr); err == io.EOF {
+			if len(idx.Members) < 2 {
+				return nil, ErrGzipSingleMember
+			}
+			return idx, nil
+		} else if err != nil {
+			return nil, errors.Wrap(err, "BuildGzipIndex")
+		}
+	}
+}
+
+// MaxMemberSize return the largest uncompressed size of member
+func (idx *GzipIndex) MaxMemberSize() int64 {
+	var max int64
+	for i, m := range idx.Members {
+		end := idx.USize
+		if i+1 < len(idx.Members) {
+			end = idx.Members[i+1].Start
+		}
+		if end-m.Start > max {
+			max = end - m.Start
+		}
+	}
+	return max
+}
+
+// WriteTo write index in compact binary format
+func (idx *GzipIndex) WriteTo(w io.Writer) (int64, error) {
+	buf := append([]byte(nil), gzipIndexMagic...)
+	var tmp [binary.MaxVarintLen64]byte
+	put := func(v int64) {
+		n := binary.PutVarint(tmp[:], v)
+		buf = append(buf, tmp[:n]...)
+	}
+	put(idx.Size)
+	put(idx.USize)
+	put(int64(len(idx.Members)))
+	var prev GzipMember
+	for _, m := range idx.Members {
+		put(m.Offset - prev.Offset)
+		put(m.Start - prev.Start)
+		prev = m
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// ReadGzipIndex read index written by WriteTo
+func ReadGzipIndex(r io.Reader) (*GzipIndex, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(gzipIndexMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || !bytes.Equal(magic, gzipIndexMagic) {
+		return nil, errors.New("not a ttail gzip index")
+	}
+	var err error
+	get := func() int64 {
+		if err != nil {
+			return 0
+		}
+		var v int64
+		v, err = binary.ReadVarint(br)
+		return v
+	}
+	idx := &GzipIndex{Size: get(), USize: get()}
+	count := get()
+	if err == nil && count < 1 {
+		err = errors.New("bad members count")
+	}
+	var m GzipMember
+	for i := int64(0); i < count && err == nil; i++ {
+		m.Offset += get()
+		m.Start += get()
+		idx.Members = append(idx.Members, m)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "ReadGzipIndex")
+	}
+	return idx, nil
+}
+
+// GzipReaderAt read uncompressed data of gzip file at random offsets,
+// member with requested offset is decompressed and cached
+type GzipReaderAt struct {
+	r   io.ReaderAt
+	idx *GzipIndex
+
+	mu     sync.Mutex
+	member int
+	data   []byte
+}
+
+// NewGzipReaderAt return reader of uncompressed data of r by its index
+func NewGzipReaderAt(r io.ReaderAt, idx *GzipIndex) *GzipReaderAt {
+	return &GzipReaderAt{r: r, idx: idx, member: -1}
+}
+
+// Size of uncompressed data
+func (g *GzipReaderAt) Size() int64 {
+	return g.idx.USize
+}
+
+// ReadAt implements io.ReaderAt over uncompressed data
+func (g *GzipReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n := 0
+	for n < len(p) {
+		if off >= g.idx.USize {
+			return n, io.EOF
+		}
+		i := sort.Search(len(g.idx.Members), func(i int) bool {
+			return g.idx.Members[i].Start > off
+		}) - 1
+		if err := g.load(i); err != nil {
+			return n, err
+		}
+		m := g.idx.Members[i]
+		if off-m.Start >= int64(len(g.data)) {
+			// member is shorter than index says, file changed
+			return n, io.ErrUnexpectedEOF
+		}
+		copied := copy(p[n:], g.data[off-m.Start:])
+		n += copied
+		off += int64(copied)
+	}
+	return n, nil
+}
+
+// load decompress member i into cache
+func (g *GzipReaderAt) load(i int) error {
+	if g.member == i {
+		return nil
+	}
+	m := g.idx.Members[i]
+	end := g.idx.Size
+	if i+1 < len(g.idx.Members) {
+		end = g.idx.Members[i+1].Offset
+	}
+	zr, err := gzip.NewReader(io.NewSectionReader(g.r, m.Offset, end-m.Offset))
+	if err != nil {
+		return errors.Wrap(err, "GzipReaderAt")
+	}
+	zr.Multistream(false)
+	g.data, err = ioutil.ReadAll(zr)
+	if err != nil {
+		g.member = -1
+		return errors.Wrap(err, "GzipReaderAt")
+	}
+	g.member = i
+	return nil
+}
@@ -0,0 +1,160 @@
+package ttail
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// IndexSuffix is a suffix of index file name next to log file
+const IndexSuffix = ".ttidx"
+
+var indexMagic = []byte("TTIDX2\n")
+
+// IndexEntry is an offset of the first line of log with Time
+type IndexEntry struct {
+	Time   time.Time
+	Offset int64
+}
+
+// Index map times of log lines to offsets, it is used by FindPosition
+// to narrow binary search
+type Index struct {
+	// Size of log when index was built, smaller log means index is stale
+	Size int64
+	// Head of log when index was built, log with another head is
+	// rotated or rewritten and index is stale
+	Head        Fingerprint
+	Granularity time.Duration
+	Entries     []IndexEntry
+}
+
+// BuildIndex read whole file and add entry when time of line
+// is at least granularity after the previous entry
+func (t *TFile) BuildIndex(granularity time.Duration) (*Index, error) {
+	if s, ok := t.file.(io.Seeker); ok {
+		size, err := s.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		t.size = size
+	}
+	head, err := NewFingerprint(t.file)
+	if err != nil {
+		return nil, errors.Wrap(err, "BuildIndex")
+	}
+	idx := &Index{Size: t.size, Head: head, Granularity: granularity}
+	r := bufio.NewReaderSize(io.NewSectionReader(t.file, 0, t.size), int(t.opts.bufSize))
+	var offset int64
+	for {
+		line, err := r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// skip rest of too long line
+			n := int64(len(line))
+			for err == bufio.ErrBufferFull {
+				line, err = r.ReadSlice('\n')
+				n += int64(len(line))
+			}
+			offset += n
+			continue
+		}
+		if len(line) > 0 {
+			if tm, perr := t.ParseTime(line); perr == nil {
+				n := len(idx.Entries)
+				if n == 0 || tm.Sub(idx.Entries[n-1].Time) >= granularity {
+					idx.Entries = append(idx.Entries, IndexEntry{Time: tm, Offset: offset})
+				}
+			}
+			offset += int64(len(line))
+		}
+		if err == io.EOF {
+			return idx, nil
+		} else if err != nil {
+			return nil, errors.Wrap(err, "BuildIndex")
+		}
+	}
+}
+
+// Match report whether index is built for log r, it is checked by
+// head of log, so index of rotated or rewritten log does not match
+func (idx *Index) Match(r io.ReaderAt) (bool, error) {
+	return idx.Head.Match(r)
+}
+
+// bounds narrow binary search range [up, down] for lines from target time
+func (idx *Index) bounds(target time.Time, up, down int64) (int64, int64) {
+	i := sort.Search(len(idx.Entries), func(i int) bool {
+		return !idx.Entries[i].Time.Before(target)
+	})
+	if i > 0 && idx.Entries[i-1].Offset > up {
+		up = idx.Entries[i-1].Offset
+	}
+	if i < len(idx.Entries) && idx.Entries[i].Offset < down {
+		down = idx.Entries[i].Offset
+	}
+	return up, down
+}
+
+// WriteTo write index in compact binary format
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	buf := append([]byte(nil), indexMagic...)
+	var tmp [binary.MaxVarintLen64]byte
+	put := func(v int64) {
+		n := binary.PutVarint(tmp[:], v)
+		buf = append(buf, tmp[:n]...)
+	}
+	put(idx.Size)
+	put(idx.Head.Size)
+	put(int64(idx.Head.Hash))
+	put(int64(idx.Granularity))
+	put(int64(len(idx.Entries)))
+	var prevTime, prevOffset int64
+	for _, e := range idx.Entries {
+		// deltas of sorted entries are small
+		tm := e.Time.UnixNano()
+		put(tm - prevTime)
+		put(e.Offset - prevOffset)
+		prevTime, prevOffset = tm, e.Offset
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// ReadIndex read index written by WriteTo
+func ReadIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(indexMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != string(indexMagic) {
+		return nil, errors.New("not a ttail index")
+	}
+	var err error
+	get := func() int64 {
+		if err != nil {
+			return 0
+		}
+		var v int64
+		v, err = binary.ReadVarint(br)
+		return v
+	}
+	idx := &Index{Size: get()}
+	idx.Head = Fingerprint{Size: get(), Hash: uint64(get())}
+	idx.Granularity = time.Duration(get())
+	count := get()
+	if err == nil && count < 0 {
+		err = errors.New("bad entries count")
+	}
+	var tm, offset int64
+	for i := int64(0); i < count && err == nil; i++ {
+		tm += get()
+		offset += get()
+		idx.Entries = append(idx.Entries, IndexEntry{Time: time.Unix(0, tm), Offset: offset})
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "ReadIndex")
+	}
+	return idx, nil
+}
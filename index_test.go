@@ -0,0 +1,112 @@
+package ttail
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestIndexRoundTrip(t *testing.T) {
+	data := testLog(1000)
+	built, err := NewTimeReader(bytes.NewReader(data), 0, WithLocation(time.UTC)).BuildIndex(time.Minute)
+	if err != nil {
+		t.Fatalf("BuildIndex: %v", err)
+	}
+	if len(built.Entries) != 17 || built.Size != int64(len(data)) {
+		t.Fatalf("BuildIndex: %d entries of size %d, want 17 of size %d", len(built.Entries), built.Size, len(data))
+	}
+	tests := []struct {
+		name string
+		idx  *Index
+	}{
+		{"empty", &Index{}},
+		{"built", built},
+		{"entries", &Index{
+			Size:        1 << 40,
+			Head:        Fingerprint{Size: 1024, Hash: 1<<63 + 1},
+			Granularity: time.Second,
+			Entries: []IndexEntry{
+				{Time: testStart, Offset: 0},
+				{Time: testStart.Add(time.Nanosecond), Offset: 1},
+				{Time: testStart.Add(time.Hour), Offset: 1 << 35},
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			n, err := tt.idx.WriteTo(&buf)
+			if err != nil || n != int64(buf.Len()) {
+				t.Fatalf("WriteTo: %d, %v, want %d", n, err, buf.Len())
+			}
+			idx, err := ReadIndex(&buf)
+			if err != nil {
+				t.Fatalf("ReadIndex: %v", err)
+			}
+			if idx.Size != tt.idx.Size || idx.Head != tt.idx.Head || idx.Granularity != tt.idx.Granularity {
+				t.Errorf("ReadIndex: %+v, want %+v", idx, tt.idx)
+			}
+			if len(idx.Entries) != len(tt.idx.Entries) {
+				t.Fatalf("ReadIndex: %d entries, want %d", len(idx.Entries), len(tt.idx.Entries))
+			}
+			for i, e := range idx.Entries {
+				if !e.Time.Equal(tt.idx.Entries[i].Time) || e.Offset != tt.idx.Entries[i].Offset {
+					t.Errorf("entry %d: %v, want %v", i, e, tt.idx.Entries[i])
+				}
+			}
+		})
+	}
+
+	ok, err := built.Match(bytes.NewReader(data))
+	if err != nil || !ok {
+		t.Errorf("Match of the same log: %v, %v", ok, err)
+	}
+}
+
+func TestReadIndexBad(t *testing.T) {
+	var buf bytes.Buffer
+	idx := &Index{Entries: []IndexEntry{{Time: testStart, Offset: 10}}}
+	if _, err := idx.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"magic", []byte("TTIDX1\n")},
+		{"truncated", buf.Bytes()[:buf.Len()-1]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadIndex(bytes.NewReader(tt.data)); err == nil {
+				t.Error("ReadIndex: no error")
+			}
+		})
+	}
+}
+
+func TestIndexWindow(t *testing.T) {
+	data := testLog(5000)
+	now := testStart.Add(4999 * time.Second)
+	opts := []TimeFileOptions{
+		WithLocation(time.UTC),
+		WithClock(func() time.Time { return now }),
+		WithDuration(30 * time.Minute),
+	}
+	idx, err := NewTimeReader(bytes.NewReader(data), 0, opts...).BuildIndex(time.Minute)
+	if err != nil {
+		t.Fatalf("BuildIndex: %v", err)
+	}
+	plain := NewTimeReader(bytes.NewReader(data), 0, opts...)
+	indexed := NewTimeReader(bytes.NewReader(data), 0, append(opts, WithIndex(idx))...)
+	if err := plain.FindPosition(); err != nil {
+		t.Fatalf("FindPosition: %v", err)
+	}
+	if err := indexed.FindPosition(); err != nil {
+		t.Fatalf("FindPosition with index: %v", err)
+	}
+	if plain.Offset() != indexed.Offset() {
+		t.Errorf("offset with index %d, want %d", indexed.Offset(), plain.Offset())
+	}
+}
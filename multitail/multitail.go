@@ -0,0 +1,297 @@
+// Package multitail follow time windows of several log files,
+// it opens files, finds start of time window, reads appended lines and
+// recovers from rotation, truncation and missing files, changes of files
+// are reported by events
+package multitail
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sakateka/ttail"
+)
+
+// EventKind is a kind of file change
+type EventKind int
+
+const (
+	// Opened file, time window is found, Event.TFile may be used to parse times
+	Opened EventKind = iota
+	// Lines appended to file, Event.Data contains complete lines
+	Lines
+	// Truncated or rewritten in place file is read from the beginning
+	Truncated
+	// Rotated file is read from the beginning of the new file
+	Rotated
+	// Error of file, file is opened again on the next poll
+	Error
+	// Removed file is not followed anymore
+	Removed
+)
+
+var kindNames = []string{"opened", "lines", "truncated", "rotated", "error", "removed"}
+
+func (k EventKind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "unknown"
+}
+
+// Event of followed file
+type Event struct {
+	File string
+	Kind EventKind
+	// Type is a log type detected by Opened, empty if detection is off
+	Type  string
+	TFile *ttail.TFile
+	Data  []byte
+	Err   error
+}
+
+// Option of Tailer
+type Option func(*Tailer)
+
+// WithInterval set poll interval of files
+func WithInterval(interval time.Duration) Option {
+	return func(t *Tailer) {
+		t.interval = interval
+	}
+}
+
+// WithConfig detect log type of files by types of conf
+func WithConfig(conf ttail.Config) Option {
+	return func(t *Tailer) {
+		t.config = conf
+	}
+}
+
+// Tailer follow files and send their events
+type Tailer struct {
+	interval time.Duration
+	config   ttail.Config
+	events   chan Event
+
+	mu    sync.Mutex
+	files []*file
+	done  chan struct{}
+	once  sync.Once
+}
+
+// New create tailer of files configured by options
+func New(opt ...Option) *Tailer {
+	t := &Tailer{
+		interval: time.Second,
+		events:   make(chan Event, 64),
+		done:     make(chan struct{}),
+	}
+	for _, o := range opt {
+		o(t)
+	}
+	return t
+}
+
+// Events of files, channel is closed when Run returns
+func (t *Tailer) Events() <-chan Event {
+	return t.events
+}
+
+// Add file to follow, opts configure search of its time window
+func (t *Tailer) Add(name string, opts ...ttail.TimeFileOptions) {
+	t.mu.Lock()
+	t.files = append(t.files, &file{name: name, opts: opts})
+	t.mu.Unlock()
+}
+
+// Remove file from followed files, it is closed by the next poll
+func (t *Tailer) Remove(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, f := range t.files {
+		if f.name == name {
+			f.removed = true
+		}
+	}
+}
+
+// Close stop Run
+func (t *Tailer) Close() {
+	t.once.Do(func() { close(t.done) })
+}
+
+// Run poll files until Close
+func (t *Tailer) Run() {
+	defer close(t.events)
+	buf := make([]byte, 1<<16)
+	for {
+		var files, removed []*file
+		t.mu.Lock()
+		for _, f := range t.files {
+			if f.removed {
+				removed = append(removed, f)
+			} else {
+				files = append(files, f)
+			}
+		}
+		t.files = append([]*file(nil), files...)
+		t.mu.Unlock()
+		for _, f := range removed {
+			f.close()
+			t.send(Event{File: f.name, Kind: Removed})
+		}
+		for _, f := range files {
+			f.poll(t, buf)
+		}
+
+		select {
+		case <-t.done:
+			t.mu.Lock()
+			for _, f := range t.files {
+				f.close()
+			}
+			t.mu.Unlock()
+			return
+		case <-time.After(t.interval):
+		}
+	}
+}
+
+func (t *Tailer) send(ev Event) {
+	select {
+	case t.events <- ev:
+	case <-t.done:
+	}
+}
+
+// file state, file is nil until it is opened
+type file struct {
+	name    string
+	opts    []ttail.TimeFileOptions
+	file    *os.File
+	tail    *ttail.Tail
+	lastErr string
+	removed bool
+}
+
+func (f *file) close() {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	f.tail = nil
+}
+
+// fail close file and report error once until it changes
+func (f *file) fail(t *Tailer, err error) {
+	f.close()
+	if err.Error() == f.lastErr {
+		return
+	}
+	f.lastErr = err.Error()
+	t.send(Event{File: f.name, Kind: Error, Err: err})
+}
+
+// open file and seek to the start of time window
+func (f *file) open(t *Tailer) error {
+	file, err := os.Open(f.name)
+	if err != nil {
+		return err
+	}
+	opts := f.opts
+	var logType string
+	if t.config != nil {
+		name, _, err := ttail.DetectLogType(file, t.config)
+		if err == nil {
+			aType := t.config[name]
+			// detected type must be valid like type set by name
+			err := aType.Validate()
+			var typeOpts []ttail.TimeFileOptions
+			if err == nil {
+				typeOpts, err = aType.Options()
+			}
+			if err != nil {
+				file.Close()
+				return errors.New("Invalid options for log type " + name + ": " + err.Error())
+			}
+			logType = name
+			opts = append(append([]ttail.TimeFileOptions(nil), opts...), typeOpts...)
+		}
+	}
+	tfile := ttail.NewTimeFile(file, opts...)
+	err = tfile.FindPosition()
+	offset := tfile.Offset()
+	if err == io.EOF {
+		// nothing in time window yet, wait for new lines from the end
+		offset, err = file.Seek(0, io.SeekEnd)
+	}
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if f.tail, err = ttail.NewTail(file, offset); err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.lastErr = file, ""
+	t.send(Event{File: f.name, Kind: Opened, Type: logType, TFile: tfile})
+	return nil
+}
+
+func (f *file) reopen(t *Tailer) error {
+	file, err := os.Open(f.name)
+	if err != nil {
+		return err
+	}
+	tail, err := ttail.NewTail(file, 0)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.close()
+	f.file, f.tail = file, tail
+	t.send(Event{File: f.name, Kind: Rotated})
+	return nil
+}
+
+func (f *file) poll(t *Tailer, buf []byte) {
+	if f.file == nil {
+		if err := f.open(t); err != nil {
+			f.fail(t, err)
+			return
+		}
+	}
+	if err := f.read(t, buf); err != nil {
+		f.fail(t, err)
+	}
+}
+
+// read appended complete lines, so lines of different files are not mixed up
+func (f *file) read(t *Tailer, buf []byte) error {
+	info, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+	truncated, err := f.tail.Check()
+	if err != nil {
+		return err
+	}
+	if truncated {
+		t.send(Event{File: f.name, Kind: Truncated})
+	}
+	err = f.tail.Read(buf, func(lines []byte) error {
+		t.send(Event{File: f.name, Kind: Lines, Data: append([]byte(nil), lines...)})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// file rotated, old file is read to the end, continue with new one
+	if newInfo, err := os.Stat(f.name); err == nil && !os.SameFile(info, newInfo) {
+		return f.reopen(t)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -15,11 +16,40 @@ var DefaultConfigFile = "/etc/ttail/types.toml"
 type options struct {
 	location         *time.Location
 	duration         time.Duration
+	since            time.Time
+	until            time.Time
+	endDuration      time.Duration
 	bufSize          int64
 	stepsLimit       int
 	timeRe           *regexp.Regexp
 	timeLayout       string
 	timeFromLastLine bool
+	timeParser       TimeParser
+	clock            func() time.Time
+	poll             time.Duration
+	skew             time.Duration
+	descending       bool
+	extractors       []extractor
+	dst              DSTPolicy
+	parseFailure     ParseFailurePolicy
+	zones            map[string]int
+	progress         func(copied, total int64)
+	index            *Index
+	fields           FieldParser
+	levelRe          *regexp.Regexp
+	levelField       string
+	minLevel         string
+	traceRe          *regexp.Regexp
+	traceField       string
+	spanField        string
+}
+
+// extractor of time from line by the first group of regexp
+type extractor struct {
+	re     *regexp.Regexp
+	layout string
+	// zone is set when layout contains zone
+	zone bool
 }
 
 // TimeFileOptions set ttail options, duration, time re and layout, bufSize...
@@ -31,6 +61,8 @@ var defaultOptions = options{
 	stepsLimit: 1024,
 	timeRe:     regexp.MustCompile(`\ttimestamp=(\d{4}-\d{2}-\d{2}T\d\d:\d\d:\d\d)\t`),
 	timeLayout: "2006-01-02T15:04:05",
+	clock:      time.Now,
+	poll:       time.Second,
 }
 
 // WithDuration set tail time span
@@ -40,6 +72,84 @@ func WithDuration(t time.Duration) TimeFileOptions {
 	}
 }
 
+// WithSince set exact start time of tail instead of time span
+func WithSince(t time.Time) TimeFileOptions {
+	return func(o *options) {
+		o.since = t
+	}
+}
+
+// WithUntil set exact end time of tail, lines after it are not copied
+func WithUntil(t time.Time) TimeFileOptions {
+	return func(o *options) {
+		o.until = t
+	}
+}
+
+// WithEndDuration end tail time span d before current time or time in last line,
+// e.g. with WithDuration(10*time.Minute) lines from 10 to 5 minutes ago are copied
+// if d is 5 minutes, it is ignored if WithUntil is set
+func WithEndDuration(d time.Duration) TimeFileOptions {
+	return func(o *options) {
+		o.endDuration = d
+	}
+}
+
+// TimeWindow return bounds of time window set by options and widened by skew
+// tolerance, zero since is unknown start of window counted from the last line
+// and zero until is the end of log, it helps to limit reading of sources
+// which can't be searched, e.g. output of commands
+func TimeWindow(opt ...TimeFileOptions) (since, until time.Time) {
+	o := defaultOptions
+	for _, f := range opt {
+		f(&o)
+	}
+	switch {
+	case !o.since.IsZero():
+		since = o.since.Add(-o.skew)
+	case !o.timeFromLastLine:
+		since = o.clock().Add(-o.duration - o.skew)
+	}
+	switch {
+	case !o.until.IsZero():
+		until = o.until.Add(o.skew)
+	case o.endDuration > 0 && (!o.timeFromLastLine || !o.since.IsZero()):
+		until = o.clock().Add(-o.endDuration + o.skew)
+	}
+	return since, until
+}
+
+// WithClock set source of current time of tail time span, e.g. fixed time
+// to evaluate time span relative to a time in the past
+func WithClock(clock func() time.Time) TimeFileOptions {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithPollInterval set how often Follow checks file for appended lines
+func WithPollInterval(d time.Duration) TimeFileOptions {
+	return func(o *options) {
+		o.poll = d
+	}
+}
+
+// WithSkewTolerance widen time span by d to compensate drift of clock
+// of log writer relative to the current host
+func WithSkewTolerance(d time.Duration) TimeFileOptions {
+	return func(o *options) {
+		o.skew = d
+	}
+}
+
+// WithDescending set order of log written newest first, time window
+// is at the start of file and it is copied in reverse order of lines
+func WithDescending(descending bool) TimeFileOptions {
+	return func(o *options) {
+		o.descending = descending
+	}
+}
+
 // WithTimeFromLastLine determines where to take time for tail time span
 func WithTimeFromLastLine(timeFromLastLine bool) TimeFileOptions {
 	return func(o *options) {
@@ -47,6 +157,79 @@ func WithTimeFromLastLine(timeFromLastLine bool) TimeFileOptions {
 	}
 }
 
+// WithLocation set location of times without zone
+func WithLocation(loc *time.Location) TimeFileOptions {
+	return func(o *options) {
+		o.location = loc
+	}
+}
+
+// WithProgress set callback reporting bytes copied by CopyTo of total window size
+func WithProgress(progress func(copied, total int64)) TimeFileOptions {
+	return func(o *options) {
+		o.progress = progress
+	}
+}
+
+// WithIndex set index of file to narrow time search
+func WithIndex(idx *Index) TimeFileOptions {
+	return func(o *options) {
+		o.index = idx
+	}
+}
+
+// WithFieldParser set parser of fields for records
+func WithFieldParser(parse FieldParser) TimeFileOptions {
+	return func(o *options) {
+		o.fields = parse
+	}
+}
+
+// WithLevelReAsStr compile string to regexp for level extraction,
+// the first group of regexp is a level
+func WithLevelReAsStr(levelRe string) TimeFileOptions {
+	re := regexp.MustCompile(levelRe)
+	return func(o *options) {
+		o.levelRe = re
+	}
+}
+
+// WithLevelField take level from field of line, fields parser must be set
+func WithLevelField(field string) TimeFileOptions {
+	return func(o *options) {
+		o.levelField = field
+	}
+}
+
+// TimeParser extract time from line instead of time regexp and layout,
+// it returns ErrNoTime for lines without time
+type TimeParser func(line []byte) (time.Time, error)
+
+// WithTimeParser set parser of time in lines, e.g. loaded from wasm module
+func WithTimeParser(parse TimeParser) TimeFileOptions {
+	return func(o *options) {
+		o.timeParser = parse
+	}
+}
+
+// WithTraceReAsStr compile string to regexp for trace context extraction,
+// groups trace_id and span_id or the first and the second groups are used
+func WithTraceReAsStr(traceRe string) TimeFileOptions {
+	re := regexp.MustCompile(traceRe)
+	return func(o *options) {
+		o.traceRe = re
+	}
+}
+
+// WithTraceFields take trace_id and span_id from fields of line by dotted paths,
+// fields parser must be set
+func WithTraceFields(traceField, spanField string) TimeFileOptions {
+	return func(o *options) {
+		o.traceField = traceField
+		o.spanField = spanField
+	}
+}
+
 // WithBufSize set buffer size for random reads
 func WithBufSize(size int64) TimeFileOptions {
 	return func(o *options) {
@@ -76,6 +259,48 @@ func WithTimeLayout(layout string) TimeFileOptions {
 	}
 }
 
+// WithDSTPolicy set choice of time for local wall clock which is ambiguous
+// or nonexistent because of daylight saving time transition
+func WithDSTPolicy(policy DSTPolicy) TimeFileOptions {
+	return func(o *options) {
+		o.dst = policy
+	}
+}
+
+// WithOnParseFailure set what CopyTo and Records do with lines
+// of time window without parsable time
+func WithOnParseFailure(policy ParseFailurePolicy) TimeFileOptions {
+	return func(o *options) {
+		o.parseFailure = policy
+	}
+}
+
+// WithMinLevel drop lines of time window below level, e.g. "ERROR"
+// copy only errors and fatal lines, lines without time follow the previous line
+func WithMinLevel(level string) TimeFileOptions {
+	return func(o *options) {
+		o.minLevel = NormalizeLevel(level)
+	}
+}
+
+// WithZoneOffsets set offsets in seconds east of UTC of zone abbreviations
+// in times, e.g. {"MSK": 3 * 3600}, they override builtin offsets
+func WithZoneOffsets(zones map[string]int) TimeFileOptions {
+	return func(o *options) {
+		o.zones = zones
+	}
+}
+
+// WithExtraTimeReAsStr add time regexp and layout tried when the main
+// time regexp and layout do not parse time of line, e.g. for raw panics
+// written between application lines
+func WithExtraTimeReAsStr(timeRe, layout string) TimeFileOptions {
+	re := regexp.MustCompile(timeRe)
+	return func(o *options) {
+		o.extractors = append(o.extractors, extractor{re: re, layout: layout})
+	}
+}
+
 // Config for ttail
 type Config map[string]Type
 
@@ -85,39 +310,260 @@ type Type struct {
 	StepsLimit int
 	TimeReStr  string
 	TimeLayout string
+	Redact     []string
+	// Fields format of line: logfmt, tskv or json
+	Fields string
+	// FieldsReStr extract fields by named groups instead of Fields format
+	FieldsReStr string
+	// LevelReStr extract level by the first group
+	LevelReStr string
+	// LevelField take level from the field
+	LevelField string
+	// TraceReStr extract trace_id and span_id by groups
+	TraceReStr string
+	// TraceField and SpanField take trace context from fields by dotted paths
+	TraceField string
+	SpanField  string
+	// Extractors are extra time regexps and layouts of mixed format lines
+	Extractors []Extractor
+	// DST is a policy for ambiguous local times: earliest, latest or utc
+	DST string
+	// Zones are offsets of zone abbreviations in times, e.g. MSK = "+03:00"
+	Zones map[string]string
+	// OnParseFailure is a policy for lines without time: include, skip or stop
+	OnParseFailure string
+	// Descending log is written newest first
+	Descending bool
+	// Wasm is a path to wasm module extracting time instead of TimeReStr
+	Wasm string
+}
+
+// Extractor is a time regexp and layout of lines in other format
+type Extractor struct {
+	TimeReStr  string
+	TimeLayout string
 }
 
-// OptionsFromConfig convert config to options list
-func OptionsFromConfig(logType string) ([]TimeFileOptions, error) {
-	if _, err := os.Stat(DefaultConfigFile); os.IsNotExist(err) {
+// Redaction replace matches of regexp in lines
+type Redaction struct {
+	Re          *regexp.Regexp
+	Replacement string
+}
+
+// ParseRedaction parse "regexp=replacement" rule,
+// replacement is after the last '=' and may refer to groups as ${1}
+func ParseRedaction(rule string) (Redaction, error) {
+	idx := strings.LastIndexByte(rule, '=')
+	if idx <= 0 {
+		return Redaction{}, errors.New("redact rule must be regexp=replacement: " + rule)
+	}
+	re, err := regexp.Compile(rule[:idx])
+	if err != nil {
+		return Redaction{}, err
+	}
+	return Redaction{Re: re, Replacement: rule[idx+1:]}, nil
+}
+
+// Redactions parse redact rules of type
+func (t Type) Redactions() ([]Redaction, error) {
+	var rules []Redaction
+	for _, rule := range t.Redact {
+		r, err := ParseRedaction(rule)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// LoadConfig read and decode config file
+func LoadConfig(path string) (Config, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil, errors.New("Config file does not exist")
 	} else if err != nil {
 		return nil, err
 	}
 
 	var conf Config
-	if _, err := toml.DecodeFile(DefaultConfigFile, &conf); err != nil {
+	if _, err := toml.DecodeFile(path, &conf); err != nil {
 		return nil, err
 	}
-	aType, ok := conf[logType]
-	if !ok {
-		return nil, errors.New("Failed to find options for log type: " + logType)
+	return conf, nil
+}
+
+// Validate check that type can be used for time search
+func (t Type) Validate() error {
+	if t.BufSize < 0 {
+		return errors.New("BufSize must be positive")
+	}
+	if t.StepsLimit < 0 {
+		return errors.New("StepsLimit must be positive")
+	}
+	if err := validateTime(t.TimeReStr, t.TimeLayout); err != nil {
+		return err
+	}
+	for _, e := range t.Extractors {
+		if e.TimeReStr == "" || e.TimeLayout == "" {
+			return errors.New("Extractors require TimeReStr and TimeLayout")
+		}
+		if err := validateTime(e.TimeReStr, e.TimeLayout); err != nil {
+			return err
+		}
+	}
+	if !DSTPolicy(t.DST).valid() {
+		return errors.New("DST must be earliest, latest or utc")
+	}
+	if !ParseFailurePolicy(t.OnParseFailure).valid() {
+		return errors.New("OnParseFailure must be include, skip or stop")
+	}
+	if _, err := t.zoneOffsets(); err != nil {
+		return err
+	}
+	if _, err := t.Redactions(); err != nil {
+		return err
+	}
+	if t.LevelReStr != "" {
+		re, err := regexp.Compile(t.LevelReStr)
+		if err != nil {
+			return err
+		}
+		if re.NumSubexp() < 1 {
+			return errors.New("LevelReStr must contain a group for level")
+		}
 	}
+	if t.LevelField != "" && t.Fields == "" && t.FieldsReStr == "" {
+		return errors.New("LevelField requires Fields or FieldsReStr")
+	}
+	if t.TraceReStr != "" {
+		re, err := regexp.Compile(t.TraceReStr)
+		if err != nil {
+			return err
+		}
+		if re.NumSubexp() < 1 {
+			return errors.New("TraceReStr must contain a group for trace_id")
+		}
+	}
+	if (t.TraceField != "" || t.SpanField != "") && t.Fields == "" && t.FieldsReStr == "" {
+		return errors.New("TraceField and SpanField require Fields or FieldsReStr")
+	}
+	if t.Wasm != "" {
+		if err := checkWasmModule(t.Wasm); err != nil {
+			return err
+		}
+	}
+	return t.validateFields()
+}
+
+// zoneOffsets parse offsets of zone abbreviations of type
+func (t Type) zoneOffsets() (map[string]int, error) {
+	if len(t.Zones) == 0 {
+		return nil, nil
+	}
+	zones := make(map[string]int, len(t.Zones))
+	for name, value := range t.Zones {
+		offset, err := ParseZoneOffset(value)
+		if err != nil {
+			return nil, errors.New("zone " + name + ": " + err.Error())
+		}
+		zones[name] = offset
+	}
+	return zones, nil
+}
+
+// validateTime check time regexp and layout, empty values are not checked
+func validateTime(timeRe, layout string) error {
+	if timeRe != "" {
+		re, err := regexp.Compile(timeRe)
+		if err != nil {
+			return err
+		}
+		if re.NumSubexp() < 1 {
+			return errors.New("TimeReStr must contain a group for time")
+		}
+	}
+	if layout != "" {
+		ref := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC).Format(layout)
+		if ref == layout {
+			return errors.New("TimeLayout does not contain any time element")
+		}
+		if _, err := time.Parse(layout, ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Options convert type to options list, type should be validated,
+// error is returned when wasm module or zone offsets can not be loaded
+func (t Type) Options() ([]TimeFileOptions, error) {
 	var opts []TimeFileOptions
-	if aType.BufSize != 0 {
-		opts = append(opts, WithBufSize(aType.BufSize))
+	if t.BufSize != 0 {
+		opts = append(opts, WithBufSize(t.BufSize))
+	}
+
+	if t.StepsLimit != 0 {
+		opts = append(opts, WithStepsLimit(t.StepsLimit))
+	}
+
+	if t.TimeReStr != "" {
+		opts = append(opts, WithTimeReAsStr(t.TimeReStr))
+	}
+
+	if t.TimeLayout != "" {
+		opts = append(opts, WithTimeLayout(t.TimeLayout))
+	}
+
+	if parse, err := t.FieldParser(); err == nil && parse != nil {
+		opts = append(opts, WithFieldParser(parse))
+	}
+
+	if t.LevelReStr != "" {
+		opts = append(opts, WithLevelReAsStr(t.LevelReStr))
 	}
 
-	if aType.StepsLimit != 0 {
-		opts = append(opts, WithStepsLimit(aType.StepsLimit))
+	if t.LevelField != "" {
+		opts = append(opts, WithLevelField(t.LevelField))
+	}
+
+	if t.TraceReStr != "" {
+		opts = append(opts, WithTraceReAsStr(t.TraceReStr))
+	}
+
+	if t.TraceField != "" || t.SpanField != "" {
+		opts = append(opts, WithTraceFields(t.TraceField, t.SpanField))
+	}
+
+	for _, e := range t.Extractors {
+		opts = append(opts, WithExtraTimeReAsStr(e.TimeReStr, e.TimeLayout))
+	}
+
+	if t.DST != "" {
+		opts = append(opts, WithDSTPolicy(DSTPolicy(t.DST)))
+	}
+
+	if t.OnParseFailure != "" {
+		opts = append(opts, WithOnParseFailure(ParseFailurePolicy(t.OnParseFailure)))
+	}
+
+	zones, err := t.zoneOffsets()
+	if err != nil {
+		return nil, err
+	}
+	if zones != nil {
+		opts = append(opts, WithZoneOffsets(zones))
 	}
 
-	if aType.TimeReStr != "" {
-		opts = append(opts, WithTimeReAsStr(aType.TimeReStr))
+	if t.Descending {
+		opts = append(opts, WithDescending(true))
 	}
 
-	if aType.TimeLayout != "" {
-		opts = append(opts, WithTimeLayout(aType.TimeLayout))
+	if t.Wasm != "" {
+		parse, err := loadWasmTimeParser(t.Wasm)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithTimeParser(parse))
 	}
 	return opts, nil
 }
@@ -0,0 +1,30 @@
+package ttail
+
+// ParseFailurePolicy choose what CopyTo and Records do with lines of time
+// window without parsable time, e.g. continuation lines of stack traces or garbage
+type ParseFailurePolicy string
+
+const (
+	// ParseFailureDefault is ParseFailureIncludeWithPrevious
+	ParseFailureDefault ParseFailurePolicy = ""
+	// ParseFailureIncludeWithPrevious keep lines as a part of the previous line
+	ParseFailureIncludeWithPrevious ParseFailurePolicy = "include"
+	// ParseFailureSkip drop lines
+	ParseFailureSkip ParseFailurePolicy = "skip"
+	// ParseFailureStop stop at the first such line with ErrNoTime
+	ParseFailureStop ParseFailurePolicy = "stop"
+)
+
+// valid report whether policy is known
+func (p ParseFailurePolicy) valid() bool {
+	switch p {
+	case ParseFailureDefault, ParseFailureIncludeWithPrevious, ParseFailureSkip, ParseFailureStop:
+		return true
+	}
+	return false
+}
+
+// drops report whether policy drops lines without time
+func (p ParseFailurePolicy) drops() bool {
+	return p == ParseFailureSkip || p == ParseFailureStop
+}
@@ -0,0 +1,164 @@
+package ttail
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// recordMaxLine is a max length of line read by Records
+const recordMaxLine = 1 << 20
+
+// Record is a parsed line of log
+type Record struct {
+	// Time of line, lines without time (e.g. stack traces) get time of previous line
+	Time    time.Time
+	Level   string
+	RawLine string
+	Fields  map[string]string
+	// TraceID and SpanID of trace context, empty if line has no trace
+	TraceID string
+	SpanID  string
+}
+
+// levelRe is a heuristic of log level in line, the first match is used
+var levelRe = regexp.MustCompile(`(?i)\b(fatal|panic|crit(?:ical)?|err(?:or)?|warn(?:ing)?|info|debug|trace)\b`)
+
+// GuessLevel return normalized level (FATAL, ERROR, WARN, INFO, DEBUG, TRACE)
+// of the first level like word in line, empty string if there is no such word
+func GuessLevel(line []byte) string {
+	m := levelRe.FindSubmatch(line)
+	if m == nil {
+		return ""
+	}
+	return NormalizeLevel(string(m[1]))
+}
+
+// NormalizeLevel convert level name to upper case and its common synonyms
+// to FATAL, ERROR and WARN
+func NormalizeLevel(level string) string {
+	switch level = strings.ToUpper(strings.TrimSpace(level)); level {
+	case "PANIC", "CRIT", "CRITICAL":
+		return "FATAL"
+	case "ERR":
+		return "ERROR"
+	case "WARNING":
+		return "WARN"
+	default:
+		return level
+	}
+}
+
+// levelSeverity of normalized levels, the most severe is the greatest
+var levelSeverity = map[string]int{"TRACE": 1, "DEBUG": 2, "INFO": 3, "WARN": 4, "ERROR": 5, "FATAL": 6}
+
+// LevelSeverity return severity of level to compare levels,
+// zero for empty or unknown level which is below all known ones
+func LevelSeverity(level string) int {
+	return levelSeverity[NormalizeLevel(level)]
+}
+
+// ParseRecord parse line by options of file, zero time means line has no time
+func (t *TFile) ParseRecord(line []byte) Record {
+	r := Record{RawLine: string(line)}
+	r.Time, _ = t.ParseTime(line)
+	if t.opts.fields != nil {
+		r.Fields = t.opts.fields(r.RawLine)
+	}
+	r.Level = t.level(line, r.Fields)
+	r.TraceID, r.SpanID = t.trace(line, r.Fields)
+	return r
+}
+
+// ParseLevel return normalized level of line by level options of file,
+// level is guessed if level options are not set
+func (t *TFile) ParseLevel(line []byte) string {
+	var fields map[string]string
+	if t.opts.levelField != "" && t.opts.fields != nil {
+		fields = t.opts.fields(string(line))
+	}
+	return t.level(line, fields)
+}
+
+func (t *TFile) level(line []byte, fields map[string]string) string {
+	switch {
+	case t.opts.levelField != "":
+		if level, ok := fields[t.opts.levelField]; ok {
+			return NormalizeLevel(level)
+		}
+		return ""
+	case t.opts.levelRe != nil:
+		if m := t.opts.levelRe.FindSubmatch(line); m != nil {
+			return NormalizeLevel(string(m[1]))
+		}
+		return ""
+	}
+	return GuessLevel(line)
+}
+
+// Records iterate over parsed lines of time window
+type Records struct {
+	t       *TFile
+	scanner *bufio.Scanner
+	rec     Record
+	last    time.Time
+	err     error
+}
+
+// Records return iterator over lines from the found offset to the end
+//
+//	records := tfile.Records()
+//	for records.Next() {
+//		r := records.Record()
+//	}
+//	err := records.Err()
+func (t *TFile) Records() *Records {
+	rs := &Records{t: t}
+	r, err := t.GetReader()
+	if err != nil {
+		rs.err = err
+		return rs
+	}
+	rs.scanner = bufio.NewScanner(r)
+	rs.scanner.Buffer(make([]byte, 0, t.opts.bufSize), recordMaxLine)
+	return rs
+}
+
+// Next parse the next line, false means end of window or error,
+// lines without time are handled by parse failure policy
+// and lines below minimal level are skipped
+func (rs *Records) Next() bool {
+	for rs.err == nil && rs.scanner.Scan() {
+		rs.rec = rs.t.ParseRecord(bytes.TrimSuffix(rs.scanner.Bytes(), []byte{'\r'}))
+		hasTime := !rs.rec.Time.IsZero()
+		if hasTime {
+			rs.last = rs.rec.Time
+		}
+		if keep, err := rs.t.keep(hasTime, rs.rec.Level); err != nil {
+			rs.err = err
+			return false
+		} else if !keep {
+			continue
+		}
+		if !hasTime {
+			rs.rec.Time = rs.last
+		}
+		return true
+	}
+	return false
+}
+
+// Record return the current record
+func (rs *Records) Record() Record {
+	return rs.rec
+}
+
+// Err return error of reading
+func (rs *Records) Err() error {
+	if rs.err != nil {
+		return rs.err
+	}
+	return rs.scanner.Err()
+}
@@ -0,0 +1,87 @@
+package ttail
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Tail read complete lines appended to file, file truncated or rewritten
+// in place is read from the beginning, it is a poll loop of Follow and multitail
+//
+//	tail, err := ttail.NewTail(file, tfile.Offset())
+//	for {
+//		truncated, err := tail.Check()
+//		err = tail.Read(buf, func(lines []byte) error { ... })
+//	}
+type Tail struct {
+	r       io.ReaderAt
+	head    Fingerprint
+	offset  int64
+	pending []byte
+}
+
+// NewTail return tail of r read from offset
+func NewTail(r io.ReaderAt, offset int64) (*Tail, error) {
+	head, err := NewFingerprint(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewTail")
+	}
+	return &Tail{r: r, head: head, offset: offset}, nil
+}
+
+// Offset of the next read, incomplete last line is read already
+func (t *Tail) Offset() int64 {
+	return t.offset
+}
+
+// Check whether file is truncated or rewritten in place, so it is
+// read from the beginning, size of file is taken only from io.Seeker
+func (t *Tail) Check() (truncated bool, err error) {
+	s, ok := t.r.(io.Seeker)
+	if !ok {
+		return false, nil
+	}
+	size, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, errors.Wrap(err, "Tail")
+	}
+	// file rewritten in place may grow over offset between polls
+	same, err := t.head.Match(t.r)
+	if err != nil {
+		return false, errors.Wrap(err, "Tail")
+	}
+	if size < t.offset || !same {
+		t.offset, t.pending = 0, t.pending[:0]
+		truncated = true
+	}
+	if t.head.Partial || !same {
+		if t.head, err = NewFingerprint(t.r); err != nil {
+			return truncated, errors.Wrap(err, "Tail")
+		}
+	}
+	return truncated, nil
+}
+
+// Read file to the end by buf and pass complete lines to fn after every read,
+// so long appended part is not kept in memory, incomplete last line waits
+// for the next Read, lines are valid only during fn call
+func (t *Tail) Read(buf []byte, fn func(lines []byte) error) error {
+	for {
+		n, err := t.r.ReadAt(buf, t.offset)
+		t.offset += int64(n)
+		t.pending = append(t.pending, buf[:n]...)
+		if idx := bytes.LastIndexByte(t.pending, '\n'); idx >= 0 {
+			if ferr := fn(t.pending[:idx+1]); ferr != nil {
+				return ferr
+			}
+			t.pending = append(t.pending[:0], t.pending[idx+1:]...)
+		}
+		if err != nil && err != io.EOF {
+			return errors.Wrap(err, "Tail")
+		} else if err == io.EOF || n == 0 {
+			return nil
+		}
+	}
+}
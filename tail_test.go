@@ -0,0 +1,136 @@
+package ttail
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// readerAtFunc is io.ReaderAt of function
+type readerAtFunc func(p []byte, off int64) (int, error)
+
+func (f readerAtFunc) ReadAt(p []byte, off int64) (int, error) {
+	return f(p, off)
+}
+
+var errTestRead = errors.New("read failed")
+
+func TestTailRead(t *testing.T) {
+	errStop := errors.New("stop")
+	tests := []struct {
+		name    string
+		r       io.ReaderAt
+		fnErr   error
+		lines   string
+		offset  int64
+		pending string
+		err     error
+	}{
+		{
+			name:   "complete lines",
+			r:      bytes.NewReader([]byte("a\nbb\nccc\n")),
+			lines:  "a\nbb\nccc\n",
+			offset: 9,
+		},
+		{
+			name:    "incomplete last line",
+			r:       bytes.NewReader([]byte("a\nbb\ncc")),
+			lines:   "a\nbb\n",
+			offset:  7,
+			pending: "cc",
+		},
+		{
+			name:  "empty",
+			r:     bytes.NewReader(nil),
+			lines: "",
+		},
+		{
+			name: "read error",
+			r: readerAtFunc(func(p []byte, off int64) (int, error) {
+				return 0, errTestRead
+			}),
+			err: errTestRead,
+		},
+		{
+			name: "data with read error",
+			r: readerAtFunc(func(p []byte, off int64) (int, error) {
+				return copy(p, "a\nb"), errTestRead
+			}),
+			lines:   "a\n",
+			offset:  3,
+			pending: "b",
+			err:     errTestRead,
+		},
+		{
+			name: "data with eof",
+			r: readerAtFunc(func(p []byte, off int64) (int, error) {
+				if off > 0 {
+					return 0, errTestRead
+				}
+				return copy(p, "a\n"), io.EOF
+			}),
+			lines:  "a\n",
+			offset: 2,
+		},
+		{
+			// lines are passed again by the next Read
+			name:    "fn error",
+			r:       bytes.NewReader([]byte("a\nbb\n")),
+			fnErr:   errStop,
+			lines:   "a\nbb\n",
+			offset:  5,
+			pending: "a\nbb\n",
+			err:     errStop,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tail := &Tail{r: tt.r}
+			var lines bytes.Buffer
+			err := tail.Read(make([]byte, 16), func(p []byte) error {
+				lines.Write(p)
+				return tt.fnErr
+			})
+			if errors.Cause(err) != tt.err {
+				t.Errorf("Read: %v, want %v", err, tt.err)
+			}
+			if lines.String() != tt.lines {
+				t.Errorf("lines %q, want %q", lines.String(), tt.lines)
+			}
+			if tail.Offset() != tt.offset {
+				t.Errorf("offset %d, want %d", tail.Offset(), tt.offset)
+			}
+			if string(tail.pending) != tt.pending {
+				t.Errorf("pending %q, want %q", tail.pending, tt.pending)
+			}
+		})
+	}
+}
+
+func TestTailReadPending(t *testing.T) {
+	data := []byte("a\nb")
+	tail := &Tail{r: readerAtFunc(func(p []byte, off int64) (int, error) {
+		if off >= int64(len(data)) {
+			return 0, io.EOF
+		}
+		return copy(p, data[off:]), nil
+	})}
+	var lines bytes.Buffer
+	fn := func(p []byte) error {
+		lines.Write(p)
+		return nil
+	}
+	if err := tail.Read(make([]byte, 2), fn); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	// incomplete line is completed by appended data
+	data = append(data, "b\nc\n"...)
+	if err := tail.Read(make([]byte, 2), fn); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if lines.String() != "a\nbb\nc\n" || tail.Offset() != int64(len(data)) {
+		t.Errorf("lines %q at %d, want %q at %d", lines.String(), tail.Offset(), "a\nbb\nc\n", len(data))
+	}
+}
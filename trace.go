@@ -0,0 +1,101 @@
+package ttail
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+var (
+	// traceIDRe and spanIDRe are heuristics of trace context ids in line
+	traceIDRe = regexp.MustCompile(`(?i)\btrace[_.-]?id\W{1,4}([0-9a-f]{32})\b`)
+	spanIDRe  = regexp.MustCompile(`(?i)\bspan[_.-]?id\W{1,4}([0-9a-f]{16})\b`)
+	// traceparentRe is a w3c traceparent header: version-trace_id-span_id-flags
+	traceparentRe = regexp.MustCompile(`(?i)\b[0-9a-f]{2}-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}\b`)
+)
+
+// ParseTrace return lower case trace_id and span_id of line by trace options
+// of file, ids are guessed if trace options are not set
+func (t *TFile) ParseTrace(line []byte) (traceID, spanID string) {
+	var fields map[string]string
+	if (t.opts.traceField != "" || t.opts.spanField != "") && t.opts.fields != nil {
+		fields = t.opts.fields(string(line))
+	}
+	return t.trace(line, fields)
+}
+
+func (t *TFile) trace(line []byte, fields map[string]string) (traceID, spanID string) {
+	switch {
+	case t.opts.traceField != "" || t.opts.spanField != "":
+		traceID, _ = FieldPath(fields, t.opts.traceField)
+		spanID, _ = FieldPath(fields, t.opts.spanField)
+	case t.opts.traceRe != nil:
+		m := t.opts.traceRe.FindSubmatch(line)
+		if m == nil {
+			return "", ""
+		}
+		traceID = string(m[1])
+		if len(m) > 2 {
+			spanID = string(m[2])
+		}
+		for i, name := range t.opts.traceRe.SubexpNames() {
+			switch name {
+			case "trace_id":
+				traceID = string(m[i])
+			case "span_id":
+				spanID = string(m[i])
+			}
+		}
+	default:
+		if m := traceIDRe.FindSubmatch(line); m != nil {
+			traceID = string(m[1])
+			if m := spanIDRe.FindSubmatch(line); m != nil {
+				spanID = string(m[1])
+			}
+		} else if m := traceparentRe.FindSubmatch(line); m != nil {
+			traceID, spanID = string(m[1]), string(m[2])
+		}
+	}
+	return strings.ToLower(traceID), strings.ToLower(spanID)
+}
+
+// FieldPath return value of field by dotted path, e.g. "trace.id",
+// nested json values of fields are looked up by the rest of path
+func FieldPath(fields map[string]string, path string) (string, bool) {
+	if path == "" || fields == nil {
+		return "", false
+	}
+	if v, ok := fields[path]; ok {
+		return v, true
+	}
+	keys := strings.Split(path, ".")
+	for i := len(keys) - 1; i > 0; i-- {
+		raw, ok := fields[strings.Join(keys[:i], ".")]
+		if !ok {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return "", false
+		}
+		for _, key := range keys[i:] {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return "", false
+			}
+			if v, ok = obj[key]; !ok {
+				return "", false
+			}
+		}
+		switch v := v.(type) {
+		case string:
+			return v, true
+		case nil, map[string]interface{}, []interface{}:
+			return "", false
+		default:
+			b, _ := json.Marshal(v)
+			return string(b), true
+		}
+	}
+	return "", false
+}
@@ -13,6 +13,9 @@ import (
 // FlagDebug enable debug output
 var FlagDebug bool
 
+// ErrNoTime returned when line does not contain time
+var ErrNoTime = errors.New("time not found in line")
+
 type bufType struct {
 	b         []byte
 	lineStart int
@@ -30,32 +33,81 @@ func (b *bufType) reset() {
 // where binary search may be used
 // currently this restriction not checked :-/
 type TFile struct {
-	opts     options
-	file     *os.File
-	fromTime time.Time
-	offset   int64
-	size     int64
-	buf      bufType
+	opts options
+	// extractors of time, the first is timeRe and timeLayout of options
+	extractors []extractor
+	file       io.ReaderAt
+	name       string
+	fromTime   time.Time
+	offset     int64
+	size       int64
+	buf        bufType
+	stats      Stats
+	// end is an offset after the last line of window found by FindPosition,
+	// the window of descending file is from end back to offset, -1 if there
+	// is no end time and window ends at the end of file
+	end int64
+	// until is a time of the end of window, zero if it is not set
+	until time.Time
+	// kept is set when the last line with time is emitted by filter
+	kept bool
+}
+
+// Stats of time search
+type Stats struct {
+	// Probes is a number of binary search steps
+	Probes int
+	// BytesRead while searching
+	BytesRead int64
+	// StepsExceeded is set when time of last line is not found in stepsLimit reads
+	StepsExceeded bool
+	// NoTime is set when time is not found in file and whole file is copied
+	NoTime bool
 }
 
-// NewTimeFile create new time searcher configured by options
+// NewTimeFile create new time searcher of file configured by options,
+// it is NewTimeReader of file with size taken on search
 func NewTimeFile(f *os.File, opt ...TimeFileOptions) *TFile {
+	return NewTimeReader(f, 0, opt...)
+}
+
+// NewTimeReader create new time searcher over r with size bytes, e.g. in memory
+// buffer or network backed reader, if r is io.Seeker (e.g. *os.File or
+// *bytes.Reader) actual size is taken on search
+func NewTimeReader(r io.ReaderAt, size int64, opt ...TimeFileOptions) *TFile {
 	tFileOptions := defaultOptions
 	for _, o := range opt {
 		o(&tFileOptions)
 	}
 
-	debug("NewTimeFile: with options %+v", tFileOptions)
+	debug("NewTimeReader: with options %+v", tFileOptions)
 
+	name := "reader"
+	if n, ok := r.(interface{ Name() string }); ok {
+		name = n.Name()
+	}
 	return &TFile{
-		opts:     tFileOptions,
-		file:     f,
-		fromTime: time.Now(),
-		buf:      bufType{b: make([]byte, tFileOptions.bufSize)},
+		opts:       tFileOptions,
+		extractors: newExtractors(tFileOptions),
+		file:       r,
+		name:       name,
+		fromTime:   tFileOptions.clock(),
+		size:       size,
+		end:        -1,
+		buf:        bufType{b: make([]byte, tFileOptions.bufSize)},
 	}
 
 }
 
+// newExtractors return time extractors of options, the main one is the first
+func newExtractors(o options) []extractor {
+	extractors := append([]extractor{{re: o.timeRe, layout: o.timeLayout}}, o.extractors...)
+	for i := range extractors {
+		extractors[i].zone = layoutHasZone(extractors[i].layout)
+	}
+	return extractors
+}
+
 func debug(format string, args ...interface{}) {
 	if FlagDebug {
 		fmt.Fprintf(os.Stderr, ">>> "+format+"\n", args...)
@@ -71,11 +123,13 @@ func (t *TFile) lastLineTime() (tm time.Time) {
 	for step := t.opts.stepsLimit; offset >= 0; offset -= t.opts.bufSize {
 		if step--; step < 0 {
 			debug("[lastLineTime]: attempts to read = %d, stop", t.opts.stepsLimit)
+			t.stats.StepsExceeded = true
 			return
 		}
 		count, err := t.file.ReadAt(t.buf.b, offset)
+		t.stats.BytesRead += int64(count)
 		if err != nil && err != io.EOF {
-			debug("[lastLineTime]: read %s at %d: %s", t.file.Name(), offset, err)
+			debug("[lastLineTime]: read %s at %d: %s", t.name, offset, err)
 			return
 		}
 
@@ -99,9 +153,7 @@ func (t *TFile) lastLineTime() (tm time.Time) {
 			line = t.buf.b[t.buf.lineStart:t.buf.lineEnd]
 			debug("[lastLineTime]: search in: %q", line)
 
-			if subm := t.opts.timeRe.FindSubmatch(line); subm != nil {
-				debug("[lastLineTime]: regexp match for: %s", subm[1])
-				tm, _ = time.ParseInLocation(t.opts.timeLayout, string(subm[1]), t.opts.location)
+			if tm, err = t.ParseTime(line); err != ErrNoTime {
 				debug("[lastLineTime]: found '%s' at %d", tm.Format(t.opts.timeLayout), offset)
 				if !tm.IsZero() {
 					t.offset = offset
@@ -134,6 +186,7 @@ func (t *TFile) readLine() ([]byte, error) {
 			t.offset = offset
 			debug("[readLine]: <for> read from %d", offset)
 			n, err := t.file.ReadAt(t.buf.b[t.buf.lineEnd:], offset)
+			t.stats.BytesRead += int64(n)
 			debug("[readLine]: <for> read n=%d bytes (err = %v)", n, err)
 			if err != nil {
 				if err != io.EOF {
@@ -205,17 +258,16 @@ func (t *TFile) findTime() (*time.Time, error) {
 		if lineLen == 0 {
 			debug("[findTime]: read junk continue from: %s", t.offset)
 			t.offset += int64(t.buf.lineEnd)
-			line, err = t.readLine()
+			if line, err = t.readLine(); err != nil {
+				break
+			}
 		}
 		debug("[findTime]: in: %s", line)
 
-		if subm := t.opts.timeRe.FindSubmatch(line); subm != nil {
-			debug("[findTime]: regexp match for: %s", subm[1])
-			tm, err = time.ParseInLocation(t.opts.timeLayout, string(subm[1]), t.opts.location)
-			if err == nil {
-				return &tm, nil
-			}
-		} else {
+		if tm, err = t.ParseTime(line); err == nil {
+			return &tm, nil
+		} else if err == ErrNoTime {
+			err = nil
 			line = line[:0]
 		}
 	}
@@ -241,27 +293,101 @@ func (t *TFile) preciseFindTime() error {
 		}
 		debug("[preciseFindTime]: nextLine[%d:%d] offset=%d", t.buf.lineStart, t.buf.lineEnd, t.offset)
 
-		if subm := t.opts.timeRe.FindSubmatch(line); subm != nil {
-			debug("[preciseFindTime]: parse as time: %s", subm[1])
-			tm, err = time.ParseInLocation(t.opts.timeLayout, string(subm[1]), t.opts.location)
+		if err != nil {
+			break
+		}
+		if tm, err = t.ParseTime(line); err != ErrNoTime {
 			if err != nil {
 				debug("[preciseFindTime]: parse time error: %s", err)
 				err = nil
 				continue
 			}
-			if t.fromTime.Sub(tm) /* actual duration */ <= t.opts.duration {
+			if t.inWindow(tm) != t.opts.descending {
 				debug("[preciseFindTime]: found line: %s, offset=%d", tm, t.offset)
 				break
 			}
 		}
+		err = nil
 	}
 	return err
 }
 
+// span is a tail time span widened by skew tolerance
+func (t *TFile) span() time.Duration {
+	return t.opts.duration + t.opts.skew
+}
+
+// inWindow report whether tm is in tail time span
+func (t *TFile) inWindow(tm time.Time) bool {
+	return t.fromTime.Sub(tm) /* actual duration */ <= t.span()
+}
+
 // FindPosition search file offset in log file
 // where time is time.now() - <tail N seconds>
 // or lastLineTime() - <tail N seconds>
+// or since time if it is set,
+// and offset of the end of window if until time or end duration is set
 func (t *TFile) FindPosition() error {
+	clock := t.fromTime
+	t.end, t.until = -1, time.Time{}
+	if err := t.findStart(); err != nil || t.stats.NoTime {
+		return err
+	}
+	until := t.opts.until
+	if until.IsZero() && t.opts.endDuration > 0 {
+		// end duration is counted from the same time as tail time span
+		ref := t.fromTime
+		if !t.opts.since.IsZero() {
+			ref = clock
+		}
+		until = ref.Add(-t.opts.endDuration)
+	}
+	if until.IsZero() {
+		return nil
+	}
+	t.until = until
+	return t.findEnd(until)
+}
+
+// findEnd search offset of the first line after until widened by skew tolerance
+func (t *TFile) findEnd(until time.Time) error {
+	opts := t.opts
+	// skew widens window after until, so it is not subtracted from since
+	opts.since, opts.until, opts.endDuration = until.Add(t.opts.skew+time.Nanosecond), time.Time{}, 0
+	opts.skew = 0
+	e := &TFile{
+		opts:       opts,
+		extractors: t.extractors,
+		file:       t.file,
+		name:       t.name,
+		fromTime:   opts.since,
+		size:       t.size,
+		end:        -1,
+		buf:        bufType{b: make([]byte, opts.bufSize)},
+	}
+	err := e.findStart()
+	t.stats.Probes += e.stats.Probes
+	t.stats.BytesRead += e.stats.BytesRead
+	if err == io.EOF {
+		// all lines are before until
+		e.offset = t.size
+	} else if err != nil {
+		return err
+	}
+	t.end = e.offset
+	if t.end > t.size {
+		t.end = t.size
+	}
+	// end before start means empty window
+	if t.opts.descending == (t.end > t.offset) {
+		t.end = t.offset
+	}
+	debug("[findEnd]: until=%s, end=%d", until.Format(t.opts.timeLayout), t.end)
+	return nil
+}
+
+// findStart search the start of time window
+func (t *TFile) findStart() error {
 	var (
 		at  *time.Time
 		err error
@@ -271,16 +397,31 @@ func (t *TFile) FindPosition() error {
 		down   int64
 	)
 
-	down, err = t.file.Seek(0, os.SEEK_END)
-	if err != nil {
-		return err
+	if s, ok := t.file.(io.Seeker); ok {
+		t.size, err = s.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
 	}
-	if t.opts.timeFromLastLine {
+	down = t.size
+	if !t.opts.since.IsZero() {
+		// search exact time instead of time span
+		t.fromTime = t.opts.since
+		t.opts.duration = 0
+	} else if t.opts.timeFromLastLine {
 		t.offset = down
-		t.fromTime = t.lastLineTime()
+		if t.opts.descending {
+			t.fromTime = t.firstLineTime()
+		} else {
+			t.fromTime = t.lastLineTime()
+		}
 		if t.fromTime.IsZero() {
-			debug("[FindPosition]: time not found, copy whole file: %s", t.file.Name())
+			debug("[FindPosition]: time not found, copy whole file: %s", t.name)
+			t.stats.NoTime = !t.stats.StepsExceeded
 			t.offset = 0
+			if t.opts.descending {
+				t.offset = t.size
+			}
 			if err != nil {
 				return err
 			}
@@ -288,10 +429,15 @@ func (t *TFile) FindPosition() error {
 		}
 	}
 	debug("[FindPosition]: Use fromTime: %s", t.fromTime.Format(t.opts.timeLayout))
+	if idx := t.opts.index; idx != nil && idx.Size <= t.size && !t.opts.descending {
+		up, down = idx.bounds(t.fromTime.Add(-t.span()), up, down)
+		debug("[FindPosition]: index bounds up=%d, down=%d", up, down)
+	}
 
 	for (down - up) > t.opts.bufSize {
 		middle = up + (down-up)/2 // avoid overflow middle
 		t.offset = middle
+		t.stats.Probes++
 
 		debug("[FindPosition]: BinSearch up=%d, down=%d, offset=%d", up, down, t.offset)
 		for at = nil; at == nil; {
@@ -301,7 +447,7 @@ func (t *TFile) FindPosition() error {
 			}
 		}
 
-		if t.fromTime.Sub(*at) /* actual duration */ > t.opts.duration {
+		if t.inWindow(*at) == t.opts.descending {
 			up = middle
 		} else {
 			down = middle
@@ -311,29 +457,302 @@ func (t *TFile) FindPosition() error {
 	debug("[FindPosition]: found?(%s) up=%d, down=%d, offset=%d", at, up, down, t.offset)
 	t.buf.reset()
 	if err := t.preciseFindTime(); err != nil {
+		if err == io.EOF && t.opts.descending {
+			// all lines after up are in time window
+			t.offset = t.size
+			return nil
+		}
 		return err
 	}
 	t.offset += int64(t.buf.lineStart)
 	return nil
 }
 
+// firstLineTime return time of the first line with time of file,
+// it is the newest time of descending log
+func (t *TFile) firstLineTime() time.Time {
+	t.offset = 0
+	t.buf.reset()
+	at, err := t.findTime()
+	if err != nil || at == nil {
+		debug("[firstLineTime]: time not found: %v", err)
+		return time.Time{}
+	}
+	return *at
+}
+
+// Bounds return times of the first and the last lines with time of file
+// without the search of time window, e.g. to check that file overlaps
+// window, times are in file order so the first is the newest one of
+// descending log, each probe reads at most stepsLimit buffers,
+// ErrNoTime is returned if time is not found
+func (t *TFile) Bounds() (first, last time.Time, err error) {
+	if s, ok := t.file.(io.Seeker); ok {
+		t.size, err = s.Seek(0, io.SeekEnd)
+		if err != nil {
+			return first, last, errors.Wrap(err, "Bounds")
+		}
+	}
+	offset, file, exceeded := t.offset, t.file, t.stats.StepsExceeded
+	defer func() {
+		t.offset, t.file = offset, file
+		t.stats.StepsExceeded = exceeded
+		t.buf.reset()
+	}()
+
+	t.offset = t.size
+	last = t.lastLineTime()
+	if last.IsZero() {
+		return first, last, ErrNoTime
+	}
+	// head probe is limited like the probe of the last line
+	t.file = io.NewSectionReader(file, 0, int64(t.opts.stepsLimit)*t.opts.bufSize)
+	first = t.firstLineTime()
+	if first.IsZero() {
+		return first, last, ErrNoTime
+	}
+	return first, last, nil
+}
+
 // CopyTo copies a file from the found
-// through FindPosition offset to the end
+// through FindPosition offset to the end,
+// lines of descending file are copied from the offset to the start in reverse order,
+// lines without time are handled by parse failure policy
+// and lines below minimal level are dropped
 func (t *TFile) CopyTo(w io.Writer) (int64, error) {
-	_, _ = t.file.Seek(t.offset, os.SEEK_SET)
+	r, err := t.GetReader()
+	if err != nil {
+		return 0, err
+	}
 	debug("[CopyTo]: Copy file from offset=%d", t.offset)
-	copied, err := io.Copy(w, t.file)
+	var filter *filterWriter
+	if t.opts.filtered() {
+		filter = &filterWriter{t: t, w: w}
+		w = filter
+	}
+	if t.opts.progress != nil {
+		total := t.bound() - t.offset
+		if t.opts.descending {
+			total = t.offset - t.bound()
+		}
+		w = &progressWriter{w: w, total: total, progress: t.opts.progress}
+	}
+	copied, err := io.Copy(w, r)
+	if err == nil && filter != nil {
+		err = filter.flush()
+	}
 	if err != nil {
 		debug("[CopyTo]: Copy only %d bytes: %s", copied, err)
 	}
 	return copied, err
 }
 
-// GetReader seek current file to target offset and return it
-func (t *TFile) GetReader() (io.Reader, error) {
-	_, err := t.file.Seek(t.offset, os.SEEK_SET)
+// progressWriter report copied bytes of time window
+type progressWriter struct {
+	w        io.Writer
+	copied   int64
+	total    int64
+	progress func(copied, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.copied += int64(n)
+	p.progress(p.copied, p.total)
+	return n, err
+}
+
+// Offset return start of time window found by FindPosition
+func (t *TFile) Offset() int64 {
+	return t.offset
+}
+
+// SetOffset set start of time window, e.g. saved by previous run
+func (t *TFile) SetOffset(offset int64) {
+	t.offset = offset
+}
+
+// Size return size of file at the time of FindPosition
+func (t *TFile) Size() int64 {
+	return t.size
+}
+
+// Stats return statistics of time search
+func (t *TFile) Stats() Stats {
+	return t.stats
+}
+
+// ParseTime extract time from line with configured time parser
+// or time regexp and layout, extractors of file are tried in turn
+func (t *TFile) ParseTime(line []byte) (time.Time, error) {
+	if t.opts.timeParser != nil {
+		return t.opts.timeParser(line)
+	}
+	err := ErrNoTime
+	for _, e := range t.extractors {
+		subm := e.re.FindSubmatch(line)
+		if subm == nil {
+			continue
+		}
+		tm, perr := t.parseTime(e, string(subm[1]))
+		if perr == nil {
+			return tm, nil
+		}
+		err = perr
+	}
+	return time.Time{}, err
+}
+
+// timeMatch return start and end of time in line and extractor of time,
+// the first extractor parsing time is used
+func (t *TFile) timeMatch(line []byte) ([]int, *extractor) {
+	if t.opts.timeParser != nil {
+		return nil, nil
+	}
+	for _, e := range t.extractors {
+		idx := e.re.FindSubmatchIndex(line)
+		if idx == nil || idx[2] < 0 {
+			continue
+		}
+		if _, err := t.parseTime(e, string(line[idx[2]:idx[3]])); err == nil {
+			return idx[2:4], &e
+		}
+	}
+	return nil, nil
+}
+
+// parseTime parse value by layout of extractor, local wall clock
+// is resolved by DST policy and zone abbreviations by zone offsets
+func (t *TFile) parseTime(e extractor, value string) (time.Time, error) {
+	if e.zone {
+		tm, err := time.ParseInLocation(e.layout, value, t.opts.location)
+		if err != nil {
+			return tm, err
+		}
+		return t.fixZone(tm), nil
+	}
+	if t.opts.dst == DSTDefault {
+		return time.ParseInLocation(e.layout, value, t.opts.location)
+	}
+	w, err := time.ParseInLocation(e.layout, value, time.UTC)
 	if err != nil {
-		return nil, err
+		return w, err
 	}
-	return t.file, nil
+	return resolveWallClock(w, t.opts.location, t.opts.dst), nil
+}
+
+// TimeIndex return start and end of time in line, nil if line does not contain time
+// or time is extracted by time parser
+func (t *TFile) TimeIndex(line []byte) []int {
+	idx, _ := t.timeMatch(line)
+	return idx
+}
+
+// ConvertTime rewrite time in line into loc location keeping time layout
+func (t *TFile) ConvertTime(line []byte, loc *time.Location) ([]byte, error) {
+	idx, e := t.timeMatch(line)
+	if idx == nil {
+		return line, ErrNoTime
+	}
+	tm, err := t.parseTime(*e, string(line[idx[0]:idx[1]]))
+	if err != nil {
+		return line, err
+	}
+	converted := make([]byte, 0, len(line)+8)
+	converted = append(converted, line[:idx[0]]...)
+	converted = tm.In(loc).AppendFormat(converted, e.layout)
+	return append(converted, line[idx[1]:]...), nil
+}
+
+// GetReader return reader of time window from the offset to the size of file
+// at the time of FindPosition, data appended later is not read,
+// descending file is read by lines from the offset to the start
+func (t *TFile) GetReader() (io.Reader, error) {
+	if t.opts.descending {
+		r := io.NewSectionReader(t.file, t.bound(), t.offset-t.bound())
+		return &reverseReader{r: r, pos: t.offset - t.bound(), chunk: t.opts.bufSize}, nil
+	}
+	if s, ok := t.file.(io.Seeker); ok && t.size == 0 {
+		// FindPosition is not called, e.g. offset is set by SetOffset
+		size, err := s.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		t.size = size
+	}
+	return io.NewSectionReader(t.file, t.offset, t.bound()-t.offset), nil
+}
+
+// bound return offset of the end of time window, it is
+// the lowest offset of window of descending file
+func (t *TFile) bound() int64 {
+	switch {
+	case t.end >= 0:
+		return t.end
+	case t.opts.descending:
+		return 0
+	}
+	return t.size
+}
+
+// reverseReader read lines of r before pos in reverse order
+type reverseReader struct {
+	r     io.ReaderAt
+	pos   int64
+	chunk int64
+	// tail is a start of line which begins before pos
+	tail    []byte
+	pending []byte
+}
+
+func (rr *reverseReader) Read(p []byte) (int, error) {
+	for len(rr.pending) == 0 {
+		if rr.pos == 0 && len(rr.tail) == 0 {
+			return 0, io.EOF
+		}
+		if err := rr.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, rr.pending)
+	rr.pending = rr.pending[n:]
+	return n, nil
+}
+
+// fill read chunk before pos and put its complete lines into pending
+func (rr *reverseReader) fill() error {
+	start := rr.pos - rr.chunk
+	if start < 0 {
+		start = 0
+	}
+	data := make([]byte, rr.pos-start, rr.pos-start+int64(len(rr.tail)))
+	if _, err := rr.r.ReadAt(data, start); err != nil && err != io.EOF {
+		return err
+	}
+	data = append(data, rr.tail...)
+	rr.pos = start
+
+	// the first line may begin in previous chunk
+	first := 0
+	if start > 0 {
+		first = bytes.IndexByte(data, '\n') + 1
+		if first == 0 || first == len(data) {
+			rr.tail = data
+			return nil
+		}
+	}
+	rr.tail = append([]byte(nil), data[:first]...)
+	lines := data[first:]
+	rr.pending = make([]byte, 0, len(lines)+1)
+	for len(lines) > 0 {
+		end := len(lines)
+		if lines[end-1] == '\n' {
+			end--
+		}
+		idx := bytes.LastIndexByte(lines[:end], '\n') + 1
+		rr.pending = append(rr.pending, lines[idx:end]...)
+		rr.pending = append(rr.pending, '\n')
+		lines = lines[:idx]
+	}
+	return nil
 }
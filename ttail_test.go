@@ -0,0 +1,129 @@
+package ttail
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// testLog return tskv log of lines written every second from testStart
+func testLog(lines int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < lines; i++ {
+		tm := testStart.Add(time.Duration(i) * time.Second)
+		fmt.Fprintf(&buf, "tskv\ttimestamp=%s\tn=%d\n", tm.Format("2006-01-02T15:04:05"), i)
+	}
+	return buf.Bytes()
+}
+
+// lineNumbers return n field of every copied line
+func lineNumbers(t *testing.T, data []byte) []int {
+	var nums []int
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		idx := strings.LastIndex(line, "\tn=")
+		n, err := strconv.Atoi(line[idx+3:])
+		if idx < 0 || err != nil {
+			t.Fatalf("bad line %q", line)
+		}
+		nums = append(nums, n)
+	}
+	return nums
+}
+
+// readerAtOnly hide io.Seeker of reader, so size is taken from argument
+type readerAtOnly struct {
+	io.ReaderAt
+}
+
+func TestNewTimeReader(t *testing.T) {
+	data := testLog(1000)
+	now := testStart.Add(999 * time.Second)
+	tests := []struct {
+		name        string
+		opts        []TimeFileOptions
+		size        int64
+		first, last int
+		err         error
+	}{
+		{
+			name:  "duration",
+			opts:  []TimeFileOptions{WithDuration(10 * time.Second)},
+			first: 989, last: 999,
+		},
+		{
+			name:  "size without seeker",
+			opts:  []TimeFileOptions{WithDuration(10 * time.Second)},
+			size:  int64(len(data)),
+			first: 989, last: 999,
+		},
+		{
+			name:  "small buffer",
+			opts:  []TimeFileOptions{WithDuration(10 * time.Second), WithBufSize(128)},
+			first: 989, last: 999,
+		},
+		{
+			name:  "since until",
+			opts:  []TimeFileOptions{WithSince(testStart.Add(100 * time.Second)), WithUntil(testStart.Add(200 * time.Second))},
+			first: 100, last: 200,
+		},
+		{
+			name:  "end duration",
+			opts:  []TimeFileOptions{WithDuration(100 * time.Second), WithEndDuration(50 * time.Second)},
+			first: 899, last: 949,
+		},
+		{
+			name: "time from last line",
+			opts: []TimeFileOptions{
+				WithDuration(5 * time.Second),
+				WithTimeFromLastLine(true),
+				WithClock(func() time.Time { return now.Add(time.Hour) }),
+			},
+			first: 994, last: 999,
+		},
+		{
+			name: "window after log",
+			opts: []TimeFileOptions{WithSince(now.Add(time.Minute))},
+			err:  io.EOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r io.ReaderAt = bytes.NewReader(data)
+			if tt.size > 0 {
+				r = readerAtOnly{r}
+			}
+			opts := append([]TimeFileOptions{
+				WithLocation(time.UTC),
+				WithClock(func() time.Time { return now }),
+			}, tt.opts...)
+			tfile := NewTimeReader(r, tt.size, opts...)
+			err := tfile.FindPosition()
+			if err != tt.err {
+				t.Fatalf("FindPosition: %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			var out bytes.Buffer
+			if _, err := tfile.CopyTo(&out); err != nil {
+				t.Fatalf("CopyTo: %v", err)
+			}
+			nums := lineNumbers(t, out.Bytes())
+			if len(nums) == 0 {
+				t.Fatalf("empty window, want lines %d-%d", tt.first, tt.last)
+			}
+			if nums[0] != tt.first || nums[len(nums)-1] != tt.last || len(nums) != tt.last-tt.first+1 {
+				t.Errorf("window lines %d-%d (%d lines), want %d-%d", nums[0], nums[len(nums)-1], len(nums), tt.first, tt.last)
+			}
+		})
+	}
+}
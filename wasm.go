@@ -0,0 +1,175 @@
+//go:build ttailwasm
+// +build ttailwasm
+
+package ttail
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// wasmParsers cache instantiated modules by path, so types share one runtime
+// of module, runtime is closed when module changes or is not used by config
+var wasmParsers = struct {
+	sync.Mutex
+	m map[string]*wasmParser
+}{m: map[string]*wasmParser{}}
+
+// wasmParser call time extraction of wasm module, module must export memory,
+// alloc(size i32) i32 returning pointer to size bytes for line and
+// parse_time(ptr i32, len i32) i64 returning unix time of line in nanoseconds
+// or 0 for lines without time, memory of alloc is reused and never freed
+type wasmParser struct {
+	mu      sync.Mutex
+	modTime time.Time
+	rt      wazero.Runtime
+	mod     api.Module
+	alloc   api.Function
+	parse   api.Function
+	ptr     uint32
+	size    uint32
+}
+
+// loadWasmTimeParser return parser of cached module of path,
+// module is loaded again when its file is modified
+func loadWasmTimeParser(path string) (TimeParser, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "wasm time parser")
+	}
+	wasmParsers.Lock()
+	defer wasmParsers.Unlock()
+	if p, ok := wasmParsers.m[path]; ok {
+		if p.modTime.Equal(info.ModTime()) {
+			return p.parseTime, nil
+		}
+		p.close()
+		delete(wasmParsers.m, path)
+	}
+	p, err := newWasmParser(path)
+	if err != nil {
+		return nil, err
+	}
+	p.modTime = info.ModTime()
+	wasmParsers.m[path] = p
+	return p.parseTime, nil
+}
+
+// releaseWasmParsers close runtimes of modules not used by types of conf
+func releaseWasmParsers(conf Config) {
+	used := map[string]bool{}
+	for _, aType := range conf {
+		used[aType.Wasm] = true
+	}
+	wasmParsers.Lock()
+	defer wasmParsers.Unlock()
+	for path, p := range wasmParsers.m {
+		if !used[path] {
+			p.close()
+			delete(wasmParsers.m, path)
+		}
+	}
+}
+
+// checkWasmModule check that module of path is valid and exports functions
+// of time parser, it is decoded by interpreter without compilation into
+// machine code and instantiation, module loaded already is not checked
+func checkWasmModule(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrap(err, "wasm time parser")
+	}
+	wasmParsers.Lock()
+	p, ok := wasmParsers.m[path]
+	wasmParsers.Unlock()
+	if ok && p.modTime.Equal(info.ModTime()) {
+		return nil
+	}
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "wasm time parser")
+	}
+	ctx := context.Background()
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter())
+	defer rt.Close(ctx)
+	compiled, err := rt.CompileModule(ctx, code)
+	if err != nil {
+		return errors.Wrap(err, "wasm time parser "+path)
+	}
+	exports := compiled.ExportedFunctions()
+	if exports["alloc"] == nil || exports["parse_time"] == nil {
+		return errors.New("wasm time parser " + path + ": module must export alloc and parse_time")
+	}
+	return nil
+}
+
+// newWasmParser instantiate wasm module in sandbox without
+// access to files and network
+func newWasmParser(path string) (*wasmParser, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "wasm time parser")
+	}
+	ctx := context.Background()
+	rt := wazero.NewRuntime(ctx)
+	// modules built by tinygo or rust wasi target import wasi
+	wasi_snapshot_preview1.MustInstantiate(ctx, rt)
+	mod, err := rt.Instantiate(ctx, code)
+	if err != nil {
+		rt.Close(ctx)
+		return nil, errors.Wrap(err, "wasm time parser "+path)
+	}
+	p := &wasmParser{
+		rt:    rt,
+		mod:   mod,
+		alloc: mod.ExportedFunction("alloc"),
+		parse: mod.ExportedFunction("parse_time"),
+	}
+	if p.alloc == nil || p.parse == nil {
+		rt.Close(ctx)
+		return nil, errors.New("wasm time parser " + path + ": module must export alloc and parse_time")
+	}
+	return p, nil
+}
+
+// close runtime of module, parser fails after it
+func (p *wasmParser) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rt.Close(context.Background())
+	p.rt = nil
+}
+
+func (p *wasmParser) parseTime(line []byte) (time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rt == nil {
+		return time.Time{}, errors.New("wasm time parser is closed")
+	}
+	ctx := context.Background()
+	if uint32(len(line)) > p.size {
+		res, err := p.alloc.Call(ctx, uint64(len(line)))
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "wasm alloc")
+		}
+		p.ptr, p.size = uint32(res[0]), uint32(len(line))
+	}
+	if !p.mod.Memory().Write(p.ptr, line) {
+		return time.Time{}, errors.New("wasm alloc: pointer out of memory")
+	}
+	res, err := p.parse.Call(ctx, uint64(p.ptr), uint64(len(line)))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "wasm parse_time")
+	}
+	if ns := int64(res[0]); ns != 0 {
+		return time.Unix(0, ns), nil
+	}
+	return time.Time{}, ErrNoTime
+}
@@ -0,0 +1,20 @@
+//go:build !ttailwasm
+// +build !ttailwasm
+
+package ttail
+
+import "errors"
+
+// loadWasmTimeParser is not available without ttailwasm build tag
+func loadWasmTimeParser(path string) (TimeParser, error) {
+	return nil, errors.New("wasm time parser " + path + ": ttail is built without ttailwasm tag")
+}
+
+// checkWasmModule is not available without ttailwasm build tag
+func checkWasmModule(path string) error {
+	_, err := loadWasmTimeParser(path)
+	return err
+}
+
+// releaseWasmParsers has nothing to release without ttailwasm build tag
+func releaseWasmParsers(conf Config) {}
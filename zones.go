@@ -0,0 +1,59 @@
+package ttail
+
+import (
+	"errors"
+	"time"
+)
+
+// zoneOffsets are offsets in seconds east of UTC of common zone abbreviations,
+// time.Parse knows only abbreviations of location and reads others as UTC
+var zoneOffsets = map[string]int{
+	"UTC": 0, "GMT": 0, "WET": 0, "WEST": 1 * 3600,
+	"CET": 1 * 3600, "CEST": 2 * 3600, "BST": 1 * 3600,
+	"EET": 2 * 3600, "EEST": 3 * 3600, "MSK": 3 * 3600,
+	"IST": 5*3600 + 1800, "CST": -6 * 3600, "CDT": -5 * 3600,
+	"EST": -5 * 3600, "EDT": -4 * 3600, "MST": -7 * 3600, "MDT": -6 * 3600,
+	"PST": -8 * 3600, "PDT": -7 * 3600, "AKST": -9 * 3600, "AKDT": -8 * 3600,
+	"HST": -10 * 3600, "JST": 9 * 3600, "KST": 9 * 3600,
+	"AEST": 10 * 3600, "AEDT": 11 * 3600, "NZST": 12 * 3600, "NZDT": 13 * 3600,
+}
+
+// ParseZoneOffset parse offset like +03:00 or -0500 into seconds east of UTC
+func ParseZoneOffset(s string) (int, error) {
+	for _, layout := range []string{"-07:00", "-0700", "-07"} {
+		if tm, err := time.Parse(layout, s); err == nil {
+			_, offset := tm.Zone()
+			return offset, nil
+		}
+	}
+	return 0, errors.New("bad zone offset, want +03:00: " + s)
+}
+
+// zoneOffset return offset of zone abbreviation, offsets of options
+// override the builtin ones
+func (t *TFile) zoneOffset(name string) (int, bool) {
+	if offset, ok := t.opts.zones[name]; ok {
+		return offset, true
+	}
+	offset, ok := zoneOffsets[name]
+	return offset, ok
+}
+
+// fixZone set offset of zone abbreviation unknown to location of file
+func (t *TFile) fixZone(tm time.Time) time.Time {
+	name, offset := tm.Zone()
+	if name == "" {
+		return tm
+	}
+	if _, ok := t.opts.zones[name]; !ok && offset != 0 {
+		// abbreviation of location, time.Parse knows its offset
+		return tm
+	}
+	fixed, ok := t.zoneOffset(name)
+	if !ok || fixed == offset {
+		return tm
+	}
+	// wall clock of tm is written time
+	return time.Date(tm.Year(), tm.Month(), tm.Day(), tm.Hour(), tm.Minute(), tm.Second(),
+		tm.Nanosecond(), time.FixedZone(name, fixed))
+}